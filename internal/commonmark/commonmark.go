@@ -0,0 +1,85 @@
+// Package commonmark provides the examples of the CommonMark spec for
+// conformance tests.
+//
+// spec.json holds the 652 examples of CommonMark spec 0.31.2
+// (https://spec.commonmark.org/0.31.2/) by John MacFarlane, licensed under
+// CC-BY-SA 4.0, in the layout of the spec's own spec.json without the source
+// line numbers.
+package commonmark
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+//go:embed spec.json
+var specJSON []byte
+
+const Version = "0.31.2"
+
+type Example struct {
+	Markdown string `json:"markdown"`
+	HTML     string `json:"html"`
+	Example  int    `json:"example"`
+	Section  string `json:"section"`
+}
+
+// Examples returns the spec examples in order.
+func Examples() ([]Example, error) {
+	var examples []Example
+	if err := json.Unmarshal(specJSON, &examples); err != nil {
+		return nil, fmt.Errorf("spec examples: %w", err)
+	}
+	return examples, nil
+}
+
+// NormalizeHTML rewrites HTML so that renderings the spec considers equal
+// compare equal. Like the spec's own test runner, it does not tell a double
+// quote from its entity.
+func NormalizeHTML(html string) string {
+	return strings.ReplaceAll(html, "&quot;", "\"")
+}
+
+// Report tallies the passing examples of every section.
+type Report struct {
+	sections []string
+	passed   map[string]int
+	total    map[string]int
+}
+
+func NewReport() *Report {
+	return &Report{passed: map[string]int{}, total: map[string]int{}}
+}
+
+func (r *Report) Add(ex Example, passed bool) {
+	if _, ok := r.total[ex.Section]; !ok {
+		r.sections = append(r.sections, ex.Section)
+	}
+	r.total[ex.Section]++
+	if passed {
+		r.passed[ex.Section]++
+	}
+}
+
+// Passed returns the number of passing examples and the number of examples.
+func (r *Report) Passed() (int, int) {
+	passed, total := 0, 0
+	for _, section := range r.sections {
+		passed += r.passed[section]
+		total += r.total[section]
+	}
+	return passed, total
+}
+
+// String lists the sections in spec order with their passing examples.
+func (r *Report) String() string {
+	var sb strings.Builder
+	for _, section := range r.sections {
+		fmt.Fprintf(&sb, "%-40s %3d/%3d\n", section, r.passed[section], r.total[section])
+	}
+	passed, total := r.Passed()
+	fmt.Fprintf(&sb, "%-40s %3d/%3d\n", "Total", passed, total)
+	return sb.String()
+}
@@ -0,0 +1,41 @@
+package commonmark
+
+import "testing"
+
+func TestExamplesShouldBeNumberedInOrder(t *testing.T) {
+	examples, err := Examples()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(examples) != 652 {
+		t.Fatalf("Examples() returned %d examples, expected 652", len(examples))
+	}
+	for i, ex := range examples {
+		if ex.Example != i+1 || ex.Section == "" {
+			t.Fatalf("Examples()[%d] = %+v, expected example %d with a section", i, ex, i+1)
+		}
+	}
+}
+
+func TestReportShouldTallySections(t *testing.T) {
+	report := NewReport()
+	report.Add(Example{Example: 1, Section: "Tabs"}, true)
+	report.Add(Example{Example: 2, Section: "Tabs"}, false)
+	report.Add(Example{Example: 3, Section: "Links"}, true)
+
+	if passed, total := report.Passed(); passed != 2 || total != 3 {
+		t.Fatalf("Passed() = %d, %d, expected 2, 3", passed, total)
+	}
+	expected := "Tabs                                       1/  2\n" +
+		"Links                                      1/  1\n" +
+		"Total                                      2/  3\n"
+	if got := report.String(); got != expected {
+		t.Fatalf("String() = %q, expected %q", got, expected)
+	}
+}
+
+func TestNormalizeHTMLShouldIgnoreQuoteEntities(t *testing.T) {
+	if NormalizeHTML("<p>&quot;a&quot;</p>") != NormalizeHTML("<p>\"a\"</p>") {
+		t.Fatal("NormalizeHTML told &quot; from \"")
+	}
+}
@@ -0,0 +1,120 @@
+// Package frontmatter holds what the front matter decoders share: the tree
+// of decoded values with their source positions and the decoding error.
+package frontmatter
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Node is a decoded value. Mappings have Fields, in the order of Keys, and
+// sequences have Items; scalars have a Value and their literal source text.
+// LineNo and Column give the position of the value in the document.
+type Node struct {
+	Value  interface{}
+	Lit    string
+	LineNo int
+	Column int
+	Items  []*Node
+	Keys   []string
+	Fields map[string]*Node
+}
+
+func (n *Node) IsMapping() bool {
+	return n != nil && n.Fields != nil
+}
+
+func (n *Node) IsSequence() bool {
+	return n != nil && n.Items != nil
+}
+
+// ToValue converts the node to maps, slices and scalar values.
+func (n *Node) ToValue() interface{} {
+	switch {
+	case n == nil:
+		return nil
+	case n.IsMapping():
+		values := make(map[string]interface{}, len(n.Fields))
+		for key, field := range n.Fields {
+			values[key] = field.ToValue()
+		}
+		return values
+	case n.IsSequence():
+		values := make([]interface{}, 0, len(n.Items))
+		for _, item := range n.Items {
+			values = append(values, item.ToValue())
+		}
+		return values
+	}
+	return n.Value
+}
+
+// Error is a decoding error at a one based column of a document line.
+type Error struct {
+	LineNo int
+	Column int
+	Msg    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("front matter line %d column %d: %s", e.LineNo+1, e.Column, e.Msg)
+}
+
+func Errorf(lineNo int, column int, format string, args ...interface{}) error {
+	return &Error{LineNo: lineNo, Column: column, Msg: fmt.Sprintf(format, args...)}
+}
+
+// Source is the text of the front matter lines, the first of them being line
+// LineNo of the document, for decoders that read it as a whole.
+type Source struct {
+	Text   string
+	LineNo int
+	starts []int
+}
+
+func NewSource(lines []string, lineNo int) *Source {
+	src := &Source{Text: strings.Join(lines, "\n"), LineNo: lineNo, starts: []int{0}}
+	offset := 0
+	for _, line := range lines {
+		offset += len(line) + 1
+		src.starts = append(src.starts, offset)
+	}
+	return src
+}
+
+// Position returns the line number and column of an offset in Text.
+func (s *Source) Position(offset int) (int, int) {
+	i := sort.Search(len(s.starts), func(i int) bool { return s.starts[i] > offset }) - 1
+	if i < 0 {
+		i = 0
+	}
+	return s.LineNo + i, offset - s.starts[i] + 1
+}
+
+// Offset returns the offset in Text a node starts at.
+func (s *Source) Offset(node *Node) int {
+	return s.starts[node.LineNo-s.LineNo] + node.Column - 1
+}
+
+func (s *Source) Errorf(offset int, format string, args ...interface{}) error {
+	lineNo, column := s.Position(offset)
+	return Errorf(lineNo, column, format, args...)
+}
+
+func (s *Source) Scalar(offset int, lit string) *Node {
+	lineNo, column := s.Position(offset)
+	return &Node{Lit: lit, LineNo: lineNo, Column: column}
+}
+
+func (s *Source) Mapping(offset int) *Node {
+	node := s.Scalar(offset, "")
+	node.Fields = map[string]*Node{}
+	return node
+}
+
+func (s *Source) Sequence(offset int) *Node {
+	node := s.Scalar(offset, "")
+	node.Items = []*Node{}
+	return node
+}
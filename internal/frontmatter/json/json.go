@@ -0,0 +1,130 @@
+// Package json decodes JSON front matter, an object that takes up the first
+// lines of a note, with the positions of its values. It reads the whole of
+// JSON with encoding/json, but rejects duplicate keys.
+package json
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/siasmey/markdown/internal/frontmatter"
+)
+
+type jsonDecoder struct {
+	src *frontmatter.Source
+	dec *json.Decoder
+}
+
+// Decode decodes the front matter lines, the first of them being line lineNo
+// of the document.
+func Decode(lines []string, lineNo int) (*frontmatter.Node, error) {
+	src := frontmatter.NewSource(lines, lineNo)
+	d := &jsonDecoder{src: src, dec: json.NewDecoder(strings.NewReader(src.Text))}
+
+	root, err := d.parseValue()
+	if err != nil {
+		return root, err
+	}
+	if !root.IsMapping() {
+		return nil, d.errorf(0, "front matter is not an object")
+	}
+
+	start := d.start()
+	if _, err := d.dec.Token(); err != io.EOF {
+		return root, d.errorf(start, "unexpected data after object")
+	}
+	return root, nil
+}
+
+func (d *jsonDecoder) errorf(offset int, format string, args ...interface{}) error {
+	return d.src.Errorf(offset, format, args...)
+}
+
+func (d *jsonDecoder) wrap(err error) error {
+	var syntax *json.SyntaxError
+	if errors.As(err, &syntax) {
+		return d.errorf(int(syntax.Offset)-1, "%s", syntax.Error())
+	}
+	return d.errorf(len(d.src.Text), "unexpected end of JSON input")
+}
+
+func (d *jsonDecoder) start() int {
+	offset := int(d.dec.InputOffset())
+	for offset < len(d.src.Text) && strings.ContainsRune(" \t\r\n,:", rune(d.src.Text[offset])) {
+		offset++
+	}
+	return offset
+}
+
+func (d *jsonDecoder) parseValue() (*frontmatter.Node, error) {
+	start := d.start()
+	tk, err := d.dec.Token()
+	if err != nil {
+		return nil, d.wrap(err)
+	}
+
+	switch tk {
+	case json.Delim('{'):
+		return d.parseObject(start)
+	case json.Delim('['):
+		return d.parseArray(start)
+	}
+
+	node := d.src.Scalar(start, d.src.Text[start:d.dec.InputOffset()])
+	node.Value = tk
+	return node, nil
+}
+
+func (d *jsonDecoder) parseObject(start int) (*frontmatter.Node, error) {
+	node := d.src.Mapping(start)
+
+	for d.dec.More() {
+		keyStart := d.start()
+		tk, err := d.dec.Token()
+		if err != nil {
+			return node, d.wrap(err)
+		}
+
+		key := tk.(string)
+		if _, exists := node.Fields[key]; exists {
+			return node, d.errorf(keyStart, "duplicate key %q", key)
+		}
+
+		value, err := d.parseValue()
+		if value != nil {
+			node.Keys = append(node.Keys, key)
+			node.Fields[key] = value
+		}
+		if err != nil {
+			return node, err
+		}
+	}
+
+	if _, err := d.dec.Token(); err != nil {
+		return node, d.wrap(err)
+	}
+	node.Lit = d.src.Text[start:d.dec.InputOffset()]
+	return node, nil
+}
+
+func (d *jsonDecoder) parseArray(start int) (*frontmatter.Node, error) {
+	node := d.src.Sequence(start)
+
+	for d.dec.More() {
+		item, err := d.parseValue()
+		if item != nil {
+			node.Items = append(node.Items, item)
+		}
+		if err != nil {
+			return node, err
+		}
+	}
+
+	if _, err := d.dec.Token(); err != nil {
+		return node, d.wrap(err)
+	}
+	node.Lit = d.src.Text[start:d.dec.InputOffset()]
+	return node, nil
+}
@@ -0,0 +1,65 @@
+package json
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecodeShouldReturnValues(t *testing.T) {
+	input := `{
+  "title": "x",
+  "n": 1.5,
+  "ok": true,
+  "none": null,
+  "list": [1, "two", {"three": 3}]
+}`
+	want := map[string]interface{}{
+		"title": "x",
+		"n":     1.5,
+		"ok":    true,
+		"none":  nil,
+		"list":  []interface{}{1.0, "two", map[string]interface{}{"three": 3.0}},
+	}
+
+	root, err := Decode(strings.Split(input, "\n"), 0)
+	if err != nil {
+		t.Fatalf("Decode(%q) failed %v", input, err)
+	}
+	if got := root.ToValue(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Decode(%q) = %#v, expected %#v", input, got, want)
+	}
+}
+
+func TestDecodeShouldReturnPositions(t *testing.T) {
+	input := "{\n  \"tags\": [\"a\",\n    \"b\"]\n}"
+
+	root, err := Decode(strings.Split(input, "\n"), 0)
+	if err != nil {
+		t.Fatalf("Decode(%q) failed %v", input, err)
+	}
+
+	item := root.Fields["tags"].Items[1]
+	if item.LineNo != 2 || item.Column != 5 || item.Lit != `"b"` {
+		t.Fatalf("Decode(%q) item at %d:%d %q, expected 2:5 \"b\"", input, item.LineNo, item.Column, item.Lit)
+	}
+}
+
+func TestDecodeShouldFail(t *testing.T) {
+	tests := map[string]string{
+		"NotObject":     "[1, 2]",
+		"MissingComma":  "{\"a\": 1 \"b\": 2}",
+		"TrailingComma": "{\"a\": 1,}",
+		"Duplicate":     "{\"a\": 1, \"a\": 2}",
+		"Unterminated":  "{\"a\": 1",
+		"TrailingData":  "{\"a\": 1} x",
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := Decode(strings.Split(input, "\n"), 0); err == nil {
+				t.Fatalf("Decode(%q) expected an error", input)
+			}
+		})
+	}
+}
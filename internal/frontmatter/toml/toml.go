@@ -0,0 +1,455 @@
+// Package toml decodes the subset of TOML that the front matter of notes
+// uses. It supports:
+//
+//   - bare, quoted and dotted keys
+//   - tables and arrays of tables
+//   - basic and literal strings, also multi-line, with the escapes of TOML
+//   - integers in decimal, hexadecimal, octal and binary, with underscores
+//   - floats, including inf and nan
+//   - booleans
+//   - arrays, which may span lines and end with a comma, and inline tables
+//   - comments
+//
+// Offset date-times, local date-times, dates and times are kept as their
+// literal strings and are not validated.
+package toml
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/siasmey/markdown/internal/frontmatter"
+)
+
+var tomlEscapes = map[byte]string{
+	'b': "\b", 't': "\t", 'n': "\n", 'f': "\f", 'r': "\r", '"': "\"", '\\': "\\",
+}
+
+type tomlDecoder struct {
+	src    *frontmatter.Source
+	pos    int
+	root   *frontmatter.Node
+	table  *frontmatter.Node
+	tables map[*frontmatter.Node]bool
+}
+
+// Decode decodes the front matter lines, the first of them being line lineNo
+// of the document.
+func Decode(lines []string, lineNo int) (*frontmatter.Node, error) {
+	d := &tomlDecoder{src: frontmatter.NewSource(lines, lineNo), tables: map[*frontmatter.Node]bool{}}
+	d.root = d.src.Mapping(0)
+	d.table = d.root
+
+	for {
+		d.skipSpace(true)
+		if d.pos >= len(d.src.Text) {
+			return d.root, nil
+		}
+
+		var err error
+		if d.peek() == '[' {
+			err = d.parseTableHeader()
+		} else {
+			err = d.parseKeyValue(d.table)
+		}
+		if err != nil {
+			return d.root, err
+		}
+
+		d.skipSpace(false)
+		if d.pos < len(d.src.Text) && d.peek() != '\n' {
+			return d.root, d.errorf(d.pos, "expected a new line")
+		}
+	}
+}
+
+func (d *tomlDecoder) peek() byte {
+	return d.src.Text[d.pos]
+}
+
+func (d *tomlDecoder) errorf(offset int, format string, args ...interface{}) error {
+	return d.src.Errorf(offset, format, args...)
+}
+
+func (d *tomlDecoder) skipSpace(newLines bool) {
+	for d.pos < len(d.src.Text) {
+		switch ch := d.peek(); {
+		case ch == ' ' || ch == '\t' || ch == '\r':
+			d.pos++
+		case ch == '\n' && newLines:
+			d.pos++
+		case ch == '#':
+			for d.pos < len(d.src.Text) && d.peek() != '\n' {
+				d.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (d *tomlDecoder) parseTableHeader() error {
+	start := d.pos
+	array := strings.HasPrefix(d.src.Text[d.pos:], "[[")
+	if array {
+		d.pos += 2
+	} else {
+		d.pos++
+	}
+
+	keys, err := d.parseKey()
+	if err != nil {
+		return err
+	}
+
+	d.skipSpace(false)
+	closing := "]"
+	if array {
+		closing = "]]"
+	}
+	if !strings.HasPrefix(d.src.Text[d.pos:], closing) {
+		return d.errorf(d.pos, "expected %q", closing)
+	}
+	d.pos += len(closing)
+
+	parent := d.root
+	for _, key := range keys[:len(keys)-1] {
+		if parent, err = d.descend(parent, key, start); err != nil {
+			return err
+		}
+	}
+
+	last := keys[len(keys)-1]
+	existing := parent.Fields[last]
+	if array {
+		if existing == nil {
+			existing = d.src.Sequence(start)
+			parent.Keys = append(parent.Keys, last)
+			parent.Fields[last] = existing
+		} else if !existing.IsSequence() {
+			return d.errorf(start, "key %q is already defined", last)
+		}
+		d.table = d.src.Mapping(start)
+		existing.Items = append(existing.Items, d.table)
+		return nil
+	}
+
+	if existing == nil {
+		existing = d.src.Mapping(start)
+		parent.Keys = append(parent.Keys, last)
+		parent.Fields[last] = existing
+	} else if !existing.IsMapping() || d.tables[existing] {
+		return d.errorf(start, "table %q is already defined", strings.Join(keys, "."))
+	}
+	d.tables[existing] = true
+	d.table = existing
+	return nil
+}
+
+func (d *tomlDecoder) descend(parent *frontmatter.Node, key string, offset int) (*frontmatter.Node, error) {
+	child := parent.Fields[key]
+	switch {
+	case child == nil:
+		child = d.src.Mapping(offset)
+		parent.Keys = append(parent.Keys, key)
+		parent.Fields[key] = child
+	case child.IsSequence() && len(child.Items) > 0 && child.Items[len(child.Items)-1].IsMapping():
+		child = child.Items[len(child.Items)-1]
+	case !child.IsMapping():
+		return nil, d.errorf(offset, "key %q is already defined", key)
+	}
+	return child, nil
+}
+
+func (d *tomlDecoder) parseKeyValue(table *frontmatter.Node) error {
+	start := d.pos
+	keys, err := d.parseKey()
+	if err != nil {
+		return err
+	}
+
+	d.skipSpace(false)
+	if d.pos >= len(d.src.Text) || d.peek() != '=' {
+		return d.errorf(d.pos, "expected '='")
+	}
+	d.pos++
+	d.skipSpace(false)
+
+	for _, key := range keys[:len(keys)-1] {
+		if table, err = d.descend(table, key, start); err != nil {
+			return err
+		}
+	}
+
+	last := keys[len(keys)-1]
+	if _, exists := table.Fields[last]; exists {
+		return d.errorf(start, "duplicate key %q", last)
+	}
+
+	value, err := d.parseValue()
+	if value != nil {
+		table.Keys = append(table.Keys, last)
+		table.Fields[last] = value
+	}
+	return err
+}
+
+func (d *tomlDecoder) parseKey() ([]string, error) {
+	keys := []string{}
+
+	for {
+		d.skipSpace(false)
+		if d.pos >= len(d.src.Text) {
+			return nil, d.errorf(d.pos, "expected a key")
+		}
+
+		switch ch := d.peek(); {
+		case ch == '"' || ch == '\'':
+			node, err := d.parseString()
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, node.Value.(string))
+		case isBareKey(ch):
+			start := d.pos
+			for d.pos < len(d.src.Text) && isBareKey(d.peek()) {
+				d.pos++
+			}
+			keys = append(keys, d.src.Text[start:d.pos])
+		default:
+			return nil, d.errorf(d.pos, "expected a key")
+		}
+
+		d.skipSpace(false)
+		if d.pos >= len(d.src.Text) || d.peek() != '.' {
+			return keys, nil
+		}
+		d.pos++
+	}
+}
+
+func isBareKey(ch byte) bool {
+	return ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z' || ch >= '0' && ch <= '9' || ch == '_' || ch == '-'
+}
+
+func (d *tomlDecoder) parseValue() (*frontmatter.Node, error) {
+	if d.pos >= len(d.src.Text) {
+		return nil, d.errorf(d.pos, "expected a value")
+	}
+
+	switch d.peek() {
+	case '"', '\'':
+		return d.parseString()
+	case '[':
+		return d.parseArray()
+	case '{':
+		return d.parseInlineTable()
+	}
+
+	start := d.pos
+	for d.pos < len(d.src.Text) && !strings.ContainsRune(",]}#\n\r", rune(d.peek())) {
+		d.pos++
+	}
+	lit := strings.TrimRight(d.src.Text[start:d.pos], " \t")
+	d.pos = start + len(lit)
+	node := d.src.Scalar(start, lit)
+
+	switch {
+	case lit == "":
+		return nil, d.errorf(start, "expected a value")
+	case lit == "true" || lit == "false":
+		node.Value = lit == "true"
+	case isTOMLDate(lit):
+		node.Value = lit
+	default:
+		value, ok := tomlNumber(lit)
+		if !ok {
+			return node, d.errorf(start, "invalid value %q", lit)
+		}
+		node.Value = value
+	}
+	return node, nil
+}
+
+func isTOMLDate(lit string) bool {
+	return len(lit) >= 8 && (lit[4] == '-' || lit[2] == ':') && strings.Trim(lit, "0123456789-:.TtZz+ ") == ""
+}
+
+func tomlNumber(lit string) (interface{}, bool) {
+	clean := strings.ReplaceAll(lit, "_", "")
+	switch strings.TrimLeft(clean, "+-") {
+	case "inf":
+		if strings.HasPrefix(clean, "-") {
+			return math.Inf(-1), true
+		}
+		return math.Inf(1), true
+	case "nan":
+		return math.NaN(), true
+	}
+
+	digits := strings.TrimLeft(clean, "+-")
+	if len(digits) > 1 && digits[0] == '0' {
+		switch next := digits[1]; {
+		case next == 'x' || next == 'o' || next == 'b':
+			if digits != clean {
+				return nil, false
+			}
+			i, err := strconv.ParseInt(clean, 0, 64)
+			return int(i), err == nil
+		case next >= '0' && next <= '9':
+			return nil, false
+		}
+	}
+
+	if i, err := strconv.ParseInt(clean, 10, 64); err == nil {
+		return int(i), true
+	}
+	if f, err := strconv.ParseFloat(clean, 64); err == nil && !strings.HasPrefix(digits, ".") && !strings.HasSuffix(digits, ".") {
+		return f, true
+	}
+	return nil, false
+}
+
+func (d *tomlDecoder) parseString() (*frontmatter.Node, error) {
+	start := d.pos
+	quote := d.peek()
+	multiLine := strings.HasPrefix(d.src.Text[d.pos:], strings.Repeat(string(quote), 3))
+
+	delimiter := string(quote)
+	if multiLine {
+		delimiter = strings.Repeat(string(quote), 3)
+	}
+	d.pos += len(delimiter)
+	if multiLine && strings.HasPrefix(d.src.Text[d.pos:], "\n") {
+		d.pos++
+	}
+
+	var sb strings.Builder
+	for {
+		if d.pos >= len(d.src.Text) || (!multiLine && d.peek() == '\n') {
+			return d.src.Scalar(start, d.src.Text[start:d.pos]), d.errorf(start, "unterminated string")
+		}
+
+		if strings.HasPrefix(d.src.Text[d.pos:], delimiter) {
+			d.pos += len(delimiter)
+			for extra := 0; multiLine && extra < 2 && d.pos < len(d.src.Text) && d.peek() == quote; extra++ {
+				sb.WriteByte(quote)
+				d.pos++
+			}
+			break
+		}
+
+		ch := d.peek()
+		if ch != '\\' || quote == '\'' {
+			sb.WriteByte(ch)
+			d.pos++
+			continue
+		}
+
+		if err := d.parseEscape(&sb, multiLine); err != nil {
+			return d.src.Scalar(start, d.src.Text[start:d.pos]), err
+		}
+	}
+
+	node := d.src.Scalar(start, d.src.Text[start:d.pos])
+	node.Value = sb.String()
+	return node, nil
+}
+
+func (d *tomlDecoder) parseEscape(sb *strings.Builder, multiLine bool) error {
+	start := d.pos
+	d.pos++
+	if d.pos >= len(d.src.Text) {
+		return d.errorf(start, "invalid escape sequence")
+	}
+
+	rest := d.src.Text[d.pos:]
+	if end := strings.IndexByte(rest, '\n'); multiLine && end >= 0 && strings.Trim(rest[:end], " \t\r") == "" {
+		for d.pos < len(d.src.Text) && strings.ContainsRune(" \t\r\n", rune(d.peek())) {
+			d.pos++
+		}
+		return nil
+	}
+
+	ch := d.peek()
+	if s, ok := tomlEscapes[ch]; ok {
+		sb.WriteString(s)
+		d.pos++
+		return nil
+	}
+
+	size := map[byte]int{'u': 4, 'U': 8}[ch]
+	if size == 0 || d.pos+1+size > len(d.src.Text) {
+		return d.errorf(start, "invalid escape sequence")
+	}
+	code, err := strconv.ParseUint(d.src.Text[d.pos+1:d.pos+1+size], 16, 32)
+	if err != nil {
+		return d.errorf(start, "invalid escape sequence")
+	}
+	sb.WriteRune(rune(code))
+	d.pos += 1 + size
+	return nil
+}
+
+func (d *tomlDecoder) parseArray() (*frontmatter.Node, error) {
+	node := d.src.Sequence(d.pos)
+	d.pos++
+
+	for {
+		d.skipSpace(true)
+		if d.pos >= len(d.src.Text) {
+			return node, d.errorf(d.src.Offset(node), "unterminated array")
+		}
+		if d.peek() == ']' {
+			d.pos++
+			node.Lit = d.src.Text[d.src.Offset(node):d.pos]
+			return node, nil
+		}
+
+		item, err := d.parseValue()
+		if item != nil {
+			node.Items = append(node.Items, item)
+		}
+		if err != nil {
+			return node, err
+		}
+
+		d.skipSpace(true)
+		if d.pos < len(d.src.Text) && d.peek() == ',' {
+			d.pos++
+		} else if d.pos >= len(d.src.Text) || d.peek() != ']' {
+			return node, d.errorf(d.pos, "expected ',' or ']'")
+		}
+	}
+}
+
+func (d *tomlDecoder) parseInlineTable() (*frontmatter.Node, error) {
+	node := d.src.Mapping(d.pos)
+	d.pos++
+
+	for first := true; ; first = false {
+		d.skipSpace(false)
+		if d.pos >= len(d.src.Text) || d.peek() == '\n' {
+			return node, d.errorf(d.src.Offset(node), "unterminated inline table")
+		}
+		if d.peek() == '}' && first {
+			d.pos++
+			return node, nil
+		}
+
+		if err := d.parseKeyValue(node); err != nil {
+			return node, err
+		}
+
+		d.skipSpace(false)
+		if d.pos < len(d.src.Text) && d.peek() == ',' {
+			d.pos++
+		} else if d.pos < len(d.src.Text) && d.peek() == '}' {
+			d.pos++
+			return node, nil
+		} else {
+			return node, d.errorf(d.pos, "expected ',' or '}'")
+		}
+	}
+}
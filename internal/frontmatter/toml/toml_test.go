@@ -0,0 +1,79 @@
+package toml
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecodeShouldReturnValues(t *testing.T) {
+	tests := map[string]struct {
+		input string
+		want  map[string]interface{}
+	}{
+		"Strings":        {"a = \"x\\ty\"\nb = 'C:\\path'\nc = \"\\u00e9\"", map[string]interface{}{"a": "x\ty", "b": "C:\\path", "c": "é"}},
+		"MultiLine":      {"a = \"\"\"\none\ntwo\"\"\"\nb = '''\nraw\\n'''", map[string]interface{}{"a": "one\ntwo", "b": "raw\\n"}},
+		"LineEndingEsc":  {"a = \"\"\"one \\\n   two\"\"\"", map[string]interface{}{"a": "one two"}},
+		"Numbers":        {"a = 1_000\nb = -3\nc = 0x1f\nd = 6.5e2\ne = +0.5", map[string]interface{}{"a": 1000, "b": -3, "c": 31, "d": 650.0, "e": 0.5}},
+		"Booleans":       {"a = true\nb = false", map[string]interface{}{"a": true, "b": false}},
+		"Dates":          {"a = 2023-01-02\nb = 1979-05-27T07:32:00Z", map[string]interface{}{"a": "2023-01-02", "b": "1979-05-27T07:32:00Z"}},
+		"Arrays":         {"a = [\n  1, # one\n  2,\n]\nb = [[\"x\"], []]", map[string]interface{}{"a": []interface{}{1, 2}, "b": []interface{}{[]interface{}{"x"}, []interface{}{}}}},
+		"InlineTable":    {"a = { x = 1, y.z = \"w\" }", map[string]interface{}{"a": map[string]interface{}{"x": 1, "y": map[string]interface{}{"z": "w"}}}},
+		"DottedKeys":     {"a.b = 1\n\"c d\".e = 2", map[string]interface{}{"a": map[string]interface{}{"b": 1}, "c d": map[string]interface{}{"e": 2}}},
+		"Tables":         {"top = 1\n[x.y]\na = 1\n[z]\nb = 2", map[string]interface{}{"top": 1, "x": map[string]interface{}{"y": map[string]interface{}{"a": 1}}, "z": map[string]interface{}{"b": 2}}},
+		"ArrayOfTables":  {"[[p]]\nn = 1\n[[p]]\nn = 2\n[p.q]\nm = 3", map[string]interface{}{"p": []interface{}{map[string]interface{}{"n": 1}, map[string]interface{}{"n": 2, "q": map[string]interface{}{"m": 3}}}}},
+		"CommentsBlanks": {"# comment\n\na = 1 # trailing\n", map[string]interface{}{"a": 1}},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			root, err := Decode(strings.Split(tc.input, "\n"), 1)
+			if err != nil {
+				t.Fatalf("Decode(%q) failed %v", tc.input, err)
+			}
+			got := root.ToValue()
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("Decode(%q) = %#v, expected %#v", tc.input, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestDecodeShouldReturnSpecialFloats(t *testing.T) {
+	root, err := Decode([]string{"a = inf", "b = -inf", "c = nan"}, 1)
+	if err != nil {
+		t.Fatalf("decodeTOML failed %v", err)
+	}
+
+	values := root.ToValue().(map[string]interface{})
+	if !math.IsInf(values["a"].(float64), 1) || !math.IsInf(values["b"].(float64), -1) || !math.IsNaN(values["c"].(float64)) {
+		t.Fatalf("decodeTOML = %#v, expected inf, -inf and nan", values)
+	}
+}
+
+func TestDecodeShouldFail(t *testing.T) {
+	tests := map[string]string{
+		"MissingEquals":   "a 1",
+		"MissingValue":    "a =",
+		"InvalidValue":    "a = yes",
+		"LeadingZero":     "a = 012",
+		"DuplicateKey":    "a = 1\na = 2",
+		"DuplicateTable":  "[a]\n[a]",
+		"TableOverValue":  "a = 1\n[a]",
+		"UnclosedString":  "a = \"x",
+		"UnclosedArray":   "a = [1, 2",
+		"BadEscape":       "a = \"\\q\"",
+		"TwoValuesOnLine": "a = 1 b = 2",
+		"UnclosedTable":   "[a",
+		"UnclosedInline":  "a = { x = 1",
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := Decode(strings.Split(input, "\n"), 1); err == nil {
+				t.Fatalf("Decode(%q) expected an error", input)
+			}
+		})
+	}
+}
@@ -0,0 +1,424 @@
+// Package yaml decodes the subset of YAML that the front matter of notes
+// uses. It supports:
+//
+//   - block mappings and block sequences indented with spaces, mappings also
+//     as sequence items
+//   - flow sequences and flow mappings, which may span lines
+//   - plain, single quoted and double quoted scalars, the latter with the
+//     escapes of Go string literals
+//   - literal (|) and folded (>) block scalars, clipped or stripped (-)
+//   - comments
+//
+// Plain scalars are resolved to nil, bools, ints, float64s or strings like
+// the core schema of YAML 1.2 does. Anchors and aliases are errors. Tags,
+// directives, multi-line plain scalars, complex keys and multiple documents
+// are not supported either; they are errors or read as plain text.
+package yaml
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/siasmey/markdown/internal/frontmatter"
+)
+
+type yamlLine struct {
+	text   string
+	indent int
+	lineNo int
+}
+
+type yamlDecoder struct {
+	lines []yamlLine
+	pos   int
+}
+
+// Decode decodes the front matter lines, the first of them being line lineNo
+// of the document. The root must be a mapping.
+func Decode(lines []string, lineNo int) (*frontmatter.Node, error) {
+	d := &yamlDecoder{}
+	for i, text := range lines {
+		trimmed := strings.TrimLeft(text, " ")
+		if strings.HasPrefix(trimmed, "\t") {
+			return nil, frontmatter.Errorf(lineNo+i, len(text)-len(trimmed)+1, "tabs are not allowed as indentation")
+		}
+		d.lines = append(d.lines, yamlLine{text: text, indent: len(text) - len(trimmed), lineNo: lineNo + i})
+	}
+
+	d.skipEmpty()
+	if d.pos >= len(d.lines) {
+		return &frontmatter.Node{Fields: map[string]*frontmatter.Node{}, LineNo: lineNo, Column: 1}, nil
+	}
+
+	root, err := d.parseBlock(0)
+	if err != nil {
+		return root, err
+	}
+	if !root.IsMapping() {
+		return nil, frontmatter.Errorf(root.LineNo, root.Column, "front matter is not a mapping")
+	}
+	if d.skipEmpty(); d.pos < len(d.lines) {
+		line := d.lines[d.pos]
+		return root, frontmatter.Errorf(line.lineNo, line.indent+1, "unexpected indentation")
+	}
+	return root, nil
+}
+
+func (d *yamlDecoder) skipEmpty() {
+	for d.pos < len(d.lines) {
+		text := strings.TrimSpace(d.lines[d.pos].text)
+		if text != "" && !strings.HasPrefix(text, "#") {
+			return
+		}
+		d.pos++
+	}
+}
+
+func (d *yamlDecoder) parseBlock(indent int) (*frontmatter.Node, error) {
+	line := d.lines[d.pos]
+	content := line.text[line.indent:]
+
+	if isSequenceItem(content) {
+		return d.parseSequence(line.indent)
+	}
+	if _, _, ok := splitKey(content); ok {
+		return d.parseMapping(line.indent)
+	}
+
+	d.pos++
+	return parseScalar(stripComment(content), line.lineNo, line.indent+1)
+}
+
+func (d *yamlDecoder) parseMapping(indent int) (*frontmatter.Node, error) {
+	node := &frontmatter.Node{Fields: map[string]*frontmatter.Node{}, LineNo: d.lines[d.pos].lineNo, Column: indent + 1}
+
+	for d.skipEmpty(); d.pos < len(d.lines); d.skipEmpty() {
+		line := d.lines[d.pos]
+		if line.indent < indent {
+			break
+		}
+		if line.indent > indent {
+			return node, frontmatter.Errorf(line.lineNo, line.indent+1, "unexpected indentation")
+		}
+
+		content := line.text[indent:]
+		if isSequenceItem(content) {
+			break
+		}
+		key, rest, ok := splitKey(content)
+		if !ok {
+			return node, frontmatter.Errorf(line.lineNo, indent+1, "expected a key")
+		}
+		if _, exists := node.Fields[key]; exists {
+			return node, frontmatter.Errorf(line.lineNo, indent+1, "duplicate key %q", key)
+		}
+
+		column := indent + len(content) - len(rest) + 1
+		value, err := d.parseValue(rest, line, indent, column)
+		node.Keys = append(node.Keys, key)
+		node.Fields[key] = value
+		if err != nil {
+			return node, err
+		}
+	}
+	return node, nil
+}
+
+func (d *yamlDecoder) parseSequence(indent int) (*frontmatter.Node, error) {
+	node := &frontmatter.Node{Items: []*frontmatter.Node{}, LineNo: d.lines[d.pos].lineNo, Column: indent + 1}
+
+	for d.skipEmpty(); d.pos < len(d.lines); d.skipEmpty() {
+		line := d.lines[d.pos]
+		content := line.text[line.indent:]
+		if line.indent != indent || !isSequenceItem(content) {
+			if line.indent > indent {
+				return node, frontmatter.Errorf(line.lineNo, line.indent+1, "unexpected indentation")
+			}
+			break
+		}
+
+		rest := strings.TrimLeft(content[1:], " ")
+		column := indent + len(content) - len(rest) + 1
+
+		if _, _, ok := splitKey(rest); ok && !isQuoted(rest) {
+			d.lines[d.pos] = yamlLine{text: strings.Repeat(" ", column-1) + rest, indent: column - 1, lineNo: line.lineNo}
+			item, err := d.parseMapping(column - 1)
+			node.Items = append(node.Items, item)
+			if err != nil {
+				return node, err
+			}
+			continue
+		}
+
+		item, err := d.parseValue(rest, line, indent, column)
+		node.Items = append(node.Items, item)
+		if err != nil {
+			return node, err
+		}
+	}
+	return node, nil
+}
+
+func (d *yamlDecoder) parseValue(rest string, line yamlLine, indent int, column int) (*frontmatter.Node, error) {
+	d.pos++
+	value := stripComment(rest)
+
+	switch {
+	case value == "":
+		d.skipEmpty()
+		if d.pos < len(d.lines) {
+			next := d.lines[d.pos]
+			if next.indent > indent || (next.indent == indent && isSequenceItem(next.text[next.indent:])) {
+				return d.parseBlock(next.indent)
+			}
+		}
+		return &frontmatter.Node{LineNo: line.lineNo, Column: column}, nil
+	case value[0] == '|' || value[0] == '>':
+		return d.parseBlockScalar(value, line, indent, column), nil
+	case value[0] == '[' || value[0] == '{':
+		for !flowClosed(value) && d.pos < len(d.lines) {
+			value += " " + strings.TrimSpace(stripComment(d.lines[d.pos].text))
+			d.pos++
+		}
+		node, rest, err := parseFlow(value, line.lineNo, column)
+		if err == nil && strings.TrimSpace(rest) != "" {
+			err = frontmatter.Errorf(line.lineNo, column+len(value)-len(rest), "unexpected text after flow collection")
+		}
+		return node, err
+	}
+	return parseScalar(value, line.lineNo, column)
+}
+
+func (d *yamlDecoder) parseBlockScalar(header string, line yamlLine, indent int, column int) *frontmatter.Node {
+	parts := []string{}
+	blockIndent := -1
+
+	for ; d.pos < len(d.lines); d.pos++ {
+		next := d.lines[d.pos]
+		if strings.TrimSpace(next.text) == "" {
+			parts = append(parts, "")
+			continue
+		}
+		if next.indent <= indent {
+			break
+		}
+		if blockIndent < 0 || next.indent < blockIndent {
+			blockIndent = next.indent
+		}
+		parts = append(parts, next.text[blockIndent:])
+	}
+
+	for len(parts) > 0 && parts[len(parts)-1] == "" {
+		parts = parts[:len(parts)-1]
+	}
+
+	sep := "\n"
+	if header[0] == '>' {
+		sep = " "
+	}
+	value := strings.Join(parts, sep)
+	if !strings.HasSuffix(header, "-") && value != "" {
+		value += "\n"
+	}
+	return &frontmatter.Node{Value: value, Lit: header, LineNo: line.lineNo, Column: column}
+}
+
+func parseFlow(text string, lineNo int, column int) (*frontmatter.Node, string, error) {
+	open := text[0]
+	closing := byte(']')
+	node := &frontmatter.Node{Lit: text, LineNo: lineNo, Column: column, Items: []*frontmatter.Node{}}
+	if open == '{' {
+		closing = '}'
+		node.Items = nil
+		node.Fields = map[string]*frontmatter.Node{}
+	}
+
+	rest := strings.TrimLeft(text[1:], " ")
+	for {
+		if rest == "" {
+			return node, "", frontmatter.Errorf(lineNo, column, "unterminated flow collection")
+		}
+		if rest[0] == closing {
+			return node, rest[1:], nil
+		}
+
+		offset := column + len(text) - len(rest)
+		var key string
+		if open == '{' {
+			k, after, ok := splitKey(rest)
+			if !ok {
+				return node, "", frontmatter.Errorf(lineNo, offset, "expected a key")
+			}
+			key = k
+			offset += len(rest) - len(after)
+			rest = after
+		}
+
+		var item *frontmatter.Node
+		var err error
+		if rest != "" && (rest[0] == '[' || rest[0] == '{') {
+			item, rest, err = parseFlow(rest, lineNo, offset)
+		} else {
+			end := flowItemEnd(rest)
+			item, err = parseScalar(strings.TrimSpace(rest[:end]), lineNo, offset)
+			rest = rest[end:]
+		}
+		if err != nil {
+			return node, "", err
+		}
+
+		if open == '{' {
+			node.Keys = append(node.Keys, key)
+			node.Fields[key] = item
+		} else {
+			node.Items = append(node.Items, item)
+		}
+
+		rest = strings.TrimLeft(rest, " ")
+		if strings.HasPrefix(rest, ",") {
+			rest = strings.TrimLeft(rest[1:], " ")
+		} else if rest == "" || rest[0] != closing {
+			return node, "", frontmatter.Errorf(lineNo, column+len(text)-len(rest), "expected ',' or '%c'", closing)
+		}
+	}
+}
+
+// flowItemEnd returns where the flow item that text starts with ends, at a
+// comma or a flow indicator, which plain scalars cannot contain.
+func flowItemEnd(text string) int {
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		ch := text[i]
+		switch {
+		case quote != 0:
+			if ch == quote {
+				quote = 0
+			}
+		case ch == '"' || ch == '\'':
+			quote = ch
+		case ch == ',' || strings.IndexByte("[]{}", ch) >= 0:
+			return i
+		}
+	}
+	return len(text)
+}
+
+func flowClosed(text string) bool {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		ch := text[i]
+		switch {
+		case quote != 0:
+			if ch == quote {
+				quote = 0
+			}
+		case ch == '"' || ch == '\'':
+			quote = ch
+		case ch == '[' || ch == '{':
+			depth++
+		case ch == ']' || ch == '}':
+			depth--
+		}
+	}
+	return depth <= 0
+}
+
+func parseScalar(lit string, lineNo int, column int) (*frontmatter.Node, error) {
+	node := &frontmatter.Node{Lit: lit, LineNo: lineNo, Column: column}
+
+	switch {
+	case strings.HasPrefix(lit, "\""):
+		if len(lit) < 2 || !strings.HasSuffix(lit, "\"") {
+			return node, frontmatter.Errorf(lineNo, column, "unterminated double quoted string")
+		}
+		value, err := strconv.Unquote(lit)
+		if err != nil {
+			return node, frontmatter.Errorf(lineNo, column, "invalid double quoted string")
+		}
+		node.Value = value
+	case strings.HasPrefix(lit, "'"):
+		if len(lit) < 2 || !strings.HasSuffix(lit, "'") {
+			return node, frontmatter.Errorf(lineNo, column, "unterminated single quoted string")
+		}
+		node.Value = strings.ReplaceAll(lit[1:len(lit)-1], "''", "'")
+	case strings.HasPrefix(lit, "&"):
+		return node, frontmatter.Errorf(lineNo, column, "anchors are not supported")
+	case strings.HasPrefix(lit, "*"):
+		return node, frontmatter.Errorf(lineNo, column, "aliases are not supported")
+	default:
+		node.Value = plainValue(lit)
+	}
+	return node, nil
+}
+
+func plainValue(lit string) interface{} {
+	switch lit {
+	case "", "~", "null", "Null", "NULL":
+		return nil
+	case "true", "True", "TRUE":
+		return true
+	case "false", "False", "FALSE":
+		return false
+	}
+
+	if i, err := strconv.Atoi(lit); err == nil {
+		return i
+	}
+	if f, err := strconv.ParseFloat(lit, 64); err == nil && strings.ContainsAny(lit, "0123456789") {
+		return f
+	}
+	return lit
+}
+
+func isSequenceItem(content string) bool {
+	return content == "-" || strings.HasPrefix(content, "- ")
+}
+
+func isQuoted(text string) bool {
+	return strings.HasPrefix(text, "\"") || strings.HasPrefix(text, "'")
+}
+
+func splitKey(content string) (string, string, bool) {
+	end := 0
+	if isQuoted(content) {
+		end = strings.IndexByte(content[1:], content[0]) + 1
+		if end == 0 {
+			return "", "", false
+		}
+	}
+
+	for i := end; i < len(content); i++ {
+		if content[i] != ':' || (i+1 < len(content) && content[i+1] != ' ') {
+			continue
+		}
+
+		key := strings.TrimSpace(content[:i])
+		if key == "" || strings.HasPrefix(key, "#") {
+			return "", "", false
+		}
+		if node, err := parseScalar(key, 0, 0); err == nil && isQuoted(key) {
+			key = node.Value.(string)
+		}
+		return key, strings.TrimLeft(content[i+1:], " "), true
+	}
+	return "", "", false
+}
+
+func stripComment(text string) string {
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		ch := text[i]
+		switch {
+		case quote != 0:
+			if ch == quote {
+				quote = 0
+			}
+		case (ch == '"' || ch == '\'') && (i == 0 || text[i-1] == ' ' || text[i-1] == '[' || text[i-1] == ','):
+			quote = ch
+		case ch == '#' && (i == 0 || text[i-1] == ' '):
+			return strings.TrimRight(text[:i], " ")
+		}
+	}
+	return strings.TrimRight(text, " ")
+}
@@ -0,0 +1,89 @@
+package yaml
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/siasmey/markdown/internal/frontmatter"
+)
+
+func TestDecodeYAMLShouldReturnValues(t *testing.T) {
+	tests := map[string]struct {
+		input string
+		want  map[string]interface{}
+	}{
+		"Scalars":       {"a: text\nb: 3\nc: 1.5\nd: true\ne: ~\nf:", map[string]interface{}{"a": "text", "b": 3, "c": 1.5, "d": true, "e": nil, "f": nil}},
+		"Quoted":        {"a: \"x: \\\"y\\\"\"\nb: 'it''s'", map[string]interface{}{"a": "x: \"y\"", "b": "it's"}},
+		"Comments":      {"# leading\na: x # trailing\nb: \"# kept\"", map[string]interface{}{"a": "x", "b": "# kept"}},
+		"FlowSequence":  {"a: [x, 'y, z', 1]", map[string]interface{}{"a": []interface{}{"x", "y, z", 1}}},
+		"FlowMapping":   {"a: {x: 1, y: [2]}", map[string]interface{}{"a": map[string]interface{}{"x": 1, "y": []interface{}{2}}}},
+		"BlockSequence": {"a:\n  - x\n  - y\nb:\n- z", map[string]interface{}{"a": []interface{}{"x", "y"}, "b": []interface{}{"z"}}},
+		"Nested":        {"a:\n  b:\n    c: d", map[string]interface{}{"a": map[string]interface{}{"b": map[string]interface{}{"c": "d"}}}},
+		"SequenceMaps":  {"a:\n  - n: 1\n    m: 2\n  - n: 3", map[string]interface{}{"a": []interface{}{map[string]interface{}{"n": 1, "m": 2}, map[string]interface{}{"n": 3}}}},
+		"LiteralBlock":  {"a: |\n  one\n   two\nb: x", map[string]interface{}{"a": "one\n two\n", "b": "x"}},
+		"FoldedBlock":   {"a: >-\n  one\n  two", map[string]interface{}{"a": "one two"}},
+		"URL":           {"a: https://example.com/x", map[string]interface{}{"a": "https://example.com/x"}},
+		"Empty":         {"", map[string]interface{}{}},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			root, err := Decode(strings.Split(tc.input, "\n"), 1)
+			if err != nil {
+				t.Fatalf("Decode(%q) failed %v", tc.input, err)
+			}
+			got := root.ToValue()
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("Decode(%q) = %#v, expected %#v", tc.input, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestDecodeYAMLShouldFail(t *testing.T) {
+	tests := map[string]string{
+		"NotMapping":     "just text",
+		"TabIndent":      "a:\n\tb: c",
+		"BadIndent":      "a: x\n  b: y",
+		"DuplicateKey":   "a: x\na: y",
+		"UnclosedQuote":  "a: \"x",
+		"UnclosedFlow":   "a: [x, y",
+		"MissingKey":     "a: x\nnot a key",
+		"FlowBadElement": "a: [x y] z",
+		"Anchor":         "anchor: &x 1",
+		"Alias":          "a: 1\nref: *x",
+		"AliasItem":      "a:\n  - *x",
+		"AliasInFlow":    "a: [x, *y]",
+		"UnclosedLines":  "tags: [a,\naliases: [b]",
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := Decode(strings.Split(input, "\n"), 1); err == nil {
+				t.Fatalf("Decode(%q) expected an error", input)
+			}
+		})
+	}
+}
+
+func TestDecodeYAMLShouldReturnErrorPosition(t *testing.T) {
+	tests := map[string]struct {
+		input  string
+		lineNo int
+		column int
+	}{
+		"Anchor": {"anchor: &x 1", 1, 9},
+		"Alias":  {"a: 1\nref: *x", 2, 6},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := Decode(strings.Split(tc.input, "\n"), 1)
+			decodeErr, ok := err.(*frontmatter.Error)
+			if !ok || decodeErr.LineNo != tc.lineNo || decodeErr.Column != tc.column {
+				t.Fatalf("Decode(%q) error = %v, expected one at line %d column %d", tc.input, err, tc.lineNo, tc.column)
+			}
+		})
+	}
+}
@@ -0,0 +1,205 @@
+// Package ast parses Markdown into a CommonMark document tree.
+package ast
+
+import (
+	"io"
+	"strings"
+
+	"github.com/siasmey/markdown/parse/lexer"
+)
+
+type NodeType string
+
+const (
+	DOCUMENT      NodeType = "Document"
+	BLOCKQUOTE    NodeType = "BlockQuote"
+	LIST          NodeType = "List"
+	ITEM          NodeType = "Item"
+	PARAGRAPH     NodeType = "Paragraph"
+	HEADING       NodeType = "Heading"
+	THEMATICBREAK NodeType = "ThematicBreak"
+	CODEBLOCK     NodeType = "CodeBlock"
+	HTMLBLOCK     NodeType = "HTMLBlock"
+	TEXT          NodeType = "Text"
+	SOFTBREAK     NodeType = "SoftBreak"
+	HARDBREAK     NodeType = "HardBreak"
+	EMPHASIS      NodeType = "Emphasis"
+	STRONG        NodeType = "Strong"
+	CODE          NodeType = "Code"
+	LINK          NodeType = "Link"
+	IMAGE         NodeType = "Image"
+	HTMLINLINE    NodeType = "HTMLInline"
+	WIKILINK      NodeType = "WikiLink"
+	EMBED         NodeType = "Embed"
+	TAG           NodeType = "Tag"
+)
+
+// Position is a place in the source. Line is zero based like the lexer's line
+// numbers, Column is a one based byte column and Offset a byte offset.
+type Position struct {
+	Line   int
+	Column int
+	Offset int
+}
+
+// Range spans the source of a node; End is exclusive.
+type Range struct {
+	Start Position
+	End   Position
+}
+
+// Node is a block or inline element of the document tree. Which of the value
+// fields are set depends on the Type:
+//
+//   - HEADING: Level
+//   - CODEBLOCK: Literal, Fenced and Info
+//   - TEXT, CODE, HTMLBLOCK, HTMLINLINE: Literal
+//   - WIKILINK, EMBED, TAG: Literal, their source, and Destination
+//   - LINK, IMAGE: Destination and Title
+//   - LIST: Ordered, Start, Marker and Tight
+//   - ITEM: Ordered, Start and Marker
+type Node struct {
+	Type       NodeType
+	Parent     *Node
+	FirstChild *Node
+	LastChild  *Node
+	Prev       *Node
+	Next       *Node
+	Range      Range
+
+	Literal     string
+	Level       int
+	Fenced      bool
+	Info        string
+	Destination string
+	Title       string
+	Ordered     bool
+	Start       int
+	Marker      string
+	Tight       bool
+
+	block
+	from, to int
+}
+
+func newNode(nodeType NodeType) *Node {
+	return &Node{Type: nodeType}
+}
+
+// Children returns the direct children of the node in order.
+func (n *Node) Children() []*Node {
+	children := []*Node{}
+	for child := n.FirstChild; child != nil; child = child.Next {
+		children = append(children, child)
+	}
+	return children
+}
+
+// Text concatenates the literal text of the node and its descendants, as used
+// for image descriptions and heading ids.
+func (n *Node) Text() string {
+	var sb strings.Builder
+	Walk(n, func(node *Node, entering bool) bool {
+		if !entering {
+			return true
+		}
+		switch node.Type {
+		case TEXT, CODE, HTMLINLINE, TAG:
+			sb.WriteString(node.Literal)
+		case SOFTBREAK, HARDBREAK:
+			sb.WriteString("\n")
+		}
+		return true
+	})
+	return sb.String()
+}
+
+func (n *Node) AppendChild(child *Node) {
+	child.Unlink()
+	child.Parent = n
+	if n.LastChild != nil {
+		n.LastChild.Next = child
+		child.Prev = n.LastChild
+	} else {
+		n.FirstChild = child
+	}
+	n.LastChild = child
+}
+
+func (n *Node) InsertAfter(sibling *Node) {
+	sibling.Unlink()
+	sibling.Next = n.Next
+	if sibling.Next != nil {
+		sibling.Next.Prev = sibling
+	}
+	sibling.Prev = n
+	n.Next = sibling
+	sibling.Parent = n.Parent
+	if sibling.Next == nil && sibling.Parent != nil {
+		sibling.Parent.LastChild = sibling
+	}
+}
+
+// Unlink removes the node from its parent, keeping its own children.
+func (n *Node) Unlink() {
+	if n.Prev != nil {
+		n.Prev.Next = n.Next
+	} else if n.Parent != nil {
+		n.Parent.FirstChild = n.Next
+	}
+	if n.Next != nil {
+		n.Next.Prev = n.Prev
+	} else if n.Parent != nil {
+		n.Parent.LastChild = n.Prev
+	}
+	n.Parent, n.Prev, n.Next = nil, nil, nil
+}
+
+// Walk calls fn for n and every node below it, once entering and once leaving
+// it. Returning false when entering a node skips its children and the call
+// for leaving it.
+func Walk(n *Node, fn func(node *Node, entering bool) bool) {
+	if !fn(n, true) {
+		return
+	}
+	for child := n.FirstChild; child != nil; {
+		next := child.Next
+		Walk(child, fn)
+		child = next
+	}
+	fn(n, false)
+}
+
+func Parse(input string) *Node {
+	doc, _ := ParseReader(strings.NewReader(input))
+	return doc
+}
+
+// ParseReader parses the document read from r. It returns the tree built from
+// what could be read together with the reader's error, if any.
+func ParseReader(r io.Reader) (*Node, error) {
+	s := lexer.NewScanner(r)
+	s.Logger = nil
+
+	p := newBlockParser()
+	var sb strings.Builder
+	start, lineNr := 0, 0
+	for {
+		tk := s.Scan()
+		if tk.TokenType == lexer.EOF || tk.TokenType == lexer.ERROR {
+			// A final line ending does not start another line.
+			if sb.Len() > 0 {
+				p.incorporateLine(sb.String(), lineNr, start)
+			}
+			break
+		}
+		if tk.TokenType == lexer.NL {
+			p.incorporateLine(sb.String(), lineNr, start)
+			sb.Reset()
+			lineNr, start = tk.LineNr, tk.EndOffset
+			continue
+		}
+		sb.WriteString(tk.Lit)
+	}
+	return p.finish(), s.Err()
+}
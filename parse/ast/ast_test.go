@@ -0,0 +1,139 @@
+package ast
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestParseShouldReturnDocument(t *testing.T) {
+	tests := map[string]struct {
+		input    string
+		expected string
+	}{
+		"Empty":          {"", "Document"},
+		"BlankLines":     {"\n\n  \n", "Document"},
+		"Paragraph":      {"text", "Document[Paragraph[Text(text)]]"},
+		"FinalNewLine":   {"text\n", "Document[Paragraph[Text(text)]]"},
+		"CRLF":           {"a\r\nb\r\n\r\nc", "Document[Paragraph[Text(a) SoftBreak Text(b)] Paragraph[Text(c)]]"},
+		"CR":             {"a\rb\r\rc", "Document[Paragraph[Text(a) SoftBreak Text(b)] Paragraph[Text(c)]]"},
+		"HeadingAndText": {"# A\nb", "Document[Heading[Text(A)] Paragraph[Text(b)]]"},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := dump(Parse(tc.input)); got != tc.expected {
+				t.Fatalf("Parse(%q) = %s, expected %s", tc.input, got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestParseReaderShouldMatchParse(t *testing.T) {
+	input := "# Title\n\n- a *b*\n- [c](d)\n\n```go\ncode\n```\n"
+
+	expected := Parse(input)
+	res, err := ParseReader(iotest.OneByteReader(strings.NewReader(input)))
+	if err != nil {
+		t.Fatalf("ParseReader(%q) returned error %v", input, err)
+	}
+	if dump(res) != dump(expected) {
+		t.Fatalf("ParseReader(%q) = %s, expected %s", input, dump(res), dump(expected))
+	}
+}
+
+func TestParseReaderShouldReturnReadError(t *testing.T) {
+	input := "# Title\ntext\n"
+	failure := errors.New("connection reset")
+	reader := io.MultiReader(strings.NewReader(input), iotest.ErrReader(failure))
+
+	res, err := ParseReader(reader)
+	if !errors.Is(err, failure) {
+		t.Fatalf("ParseReader(%q) returned error %v, expected %v", input, err, failure)
+	}
+	expected := "Document[Heading[Text(Title)] Paragraph[Text(text)]]"
+	if got := dump(res); got != expected {
+		t.Fatalf("ParseReader(%q) = %s, expected %s", input, got, expected)
+	}
+}
+
+func TestWalkShouldSkipChildren(t *testing.T) {
+	input := "a *b* [c *d*](e)"
+
+	var visited []string
+	Walk(Parse(input), func(n *Node, entering bool) bool {
+		if entering {
+			visited = append(visited, string(n.Type))
+		}
+		return n.Type != LINK
+	})
+	expected := "Document Paragraph Text Emphasis Text Text Link"
+	if got := strings.Join(visited, " "); got != expected {
+		t.Fatalf("Walk(%q) visited %s, expected %s", input, got, expected)
+	}
+}
+
+func TestNodeShouldReturnText(t *testing.T) {
+	tests := map[string]struct {
+		input    string
+		expected string
+	}{
+		"Plain":    {"plain", "plain"},
+		"Emphasis": {"a *b* **c**", "a b c"},
+		"Code":     {"a `b`", "a b"},
+		"Link":     {"[a](b) c", "a c"},
+		"Breaks":   {"a  \nb\nc", "a\nb\nc"},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := Parse(tc.input).Text(); got != tc.expected {
+				t.Fatalf("Parse(%q).Text() = %q, expected %q", tc.input, got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestNodeShouldInsertAndUnlink(t *testing.T) {
+	doc := Parse("a\n\nb")
+	first, second := doc.FirstChild, doc.LastChild
+
+	first.InsertAfter(newNode(THEMATICBREAK))
+	if got := dump(doc); got != "Document[Paragraph[Text(a)] ThematicBreak Paragraph[Text(b)]]" {
+		t.Fatalf("InsertAfter gave %s", got)
+	}
+	second.Unlink()
+	first.Unlink()
+	if got := dump(doc); got != "Document[ThematicBreak]" || doc.FirstChild != doc.LastChild {
+		t.Fatalf("Unlink gave %s", got)
+	}
+	if first.Parent != nil || first.Next != nil || len(first.Children()) != 1 {
+		t.Fatalf("Unlink left %+v", first)
+	}
+}
+
+// dump writes the tree below n in a compact form, with the literal of text
+// nodes in parentheses and the children of other nodes in brackets.
+func dump(n *Node) string {
+	var sb strings.Builder
+	sb.WriteString(string(n.Type))
+	switch n.Type {
+	case TEXT, CODE, HTMLINLINE, WIKILINK, EMBED, TAG:
+		fmt.Fprintf(&sb, "(%s)", n.Literal)
+	}
+	if n.FirstChild != nil {
+		parts := []string{}
+		for _, child := range n.Children() {
+			parts = append(parts, dump(child))
+		}
+		fmt.Fprintf(&sb, "[%s]", strings.Join(parts, " "))
+	}
+	return sb.String()
+}
+
+func span(input string, r Range) string {
+	return input[r.Start.Offset:r.End.Offset]
+}
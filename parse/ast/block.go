@@ -0,0 +1,767 @@
+package ast
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Blocks are built line by line: every line first continues as many of the
+// open blocks as it can, then may start new blocks, and what remains of it is
+// added to the innermost open block as text.
+
+const codeIndent = 4
+
+// block holds what the block parser tracks about a block while it is open.
+type block struct {
+	open        bool
+	content     []byte
+	segments    []segment
+	base        int
+	fenceChar   byte
+	fenceLength int
+	fenceOffset int
+	htmlType    int
+	padding     int
+	markerShift int
+}
+
+// segment maps the content added from one line back to its source.
+type segment struct {
+	index  int
+	line   int
+	column int
+	offset int
+}
+
+// position returns the source position of the content byte at index.
+func (b *block) position(index int) Position {
+	i := sort.Search(len(b.segments), func(i int) bool { return b.segments[i].index > index }) - 1
+	if i < 0 {
+		return Position{}
+	}
+	seg := b.segments[i]
+	return Position{Line: seg.line, Column: seg.column + index - seg.index + 1, Offset: seg.offset + index - seg.index}
+}
+
+type continuation int
+
+const (
+	matched continuation = iota
+	unmatched
+	consumed
+)
+
+type start int
+
+const (
+	noStart start = iota
+	containerStart
+	leafStart
+)
+
+type line struct {
+	nr     int
+	start  int
+	length int
+}
+
+type blockParser struct {
+	doc         *Node
+	tip         *Node
+	oldTip      *Node
+	lastMatched *Node
+	allClosed   bool
+
+	line      string
+	lineNr    int
+	lineStart int
+	prev      line
+
+	offset               int
+	column               int
+	nextNonspace         int
+	nextNonspaceColumn   int
+	indent               int
+	indented             bool
+	blank                bool
+	partiallyConsumedTab bool
+
+	refs map[string]reference
+}
+
+func newBlockParser() *blockParser {
+	doc := newNode(DOCUMENT)
+	doc.open = true
+	doc.Range.Start = Position{Column: 1}
+	return &blockParser{doc: doc, tip: doc, oldTip: doc, lastMatched: doc, allClosed: true, refs: map[string]reference{}}
+}
+
+func (p *blockParser) incorporateLine(text string, lineNr int, lineStart int) {
+	p.line, p.lineNr, p.lineStart = text, lineNr, lineStart
+	p.offset, p.column = 0, 0
+	p.blank, p.partiallyConsumedTab = false, false
+	p.oldTip = p.tip
+	defer func() {
+		p.prev = line{nr: lineNr, start: lineStart, length: len(text)}
+	}()
+
+	container := p.doc
+	for container.LastChild != nil && container.LastChild.open {
+		container = container.LastChild
+		p.findNextNonspace()
+
+		result := p.continues(container)
+		if result == consumed {
+			return
+		}
+		if result == unmatched {
+			container = container.Parent
+			break
+		}
+	}
+
+	p.allClosed = container == p.oldTip
+	p.lastMatched = container
+
+	matchedLeaf := container.Type != PARAGRAPH && acceptsLines(container.Type)
+	for !matchedLeaf {
+		p.findNextNonspace()
+
+		if !p.indented && !maySpecial(p.line[p.nextNonspace:]) {
+			p.advanceNextNonspace()
+			break
+		}
+
+		started := noStart
+		for _, blockStart := range blockStarts {
+			if started = blockStart(p, container); started != noStart {
+				break
+			}
+		}
+		if started == noStart {
+			p.advanceNextNonspace()
+			break
+		}
+		container = p.tip
+		matchedLeaf = started == leafStart
+	}
+
+	// A line that continues none of the open blocks but the paragraph is a
+	// lazy continuation line.
+	if !p.allClosed && !p.blank && p.tip.Type == PARAGRAPH {
+		p.addLine()
+		return
+	}
+
+	p.closeUnmatchedBlocks()
+
+	switch {
+	case acceptsLines(container.Type):
+		p.addLine()
+		if container.Type == HTMLBLOCK && container.htmlType >= 1 && container.htmlType <= 5 &&
+			htmlBlockClose[container.htmlType].MatchString(p.line[p.offset:]) {
+			p.finalize(container, p.position(len(p.line)))
+		}
+	case p.offset < len(p.line) && !p.blank:
+		p.addChild(PARAGRAPH, p.offset)
+		p.advanceNextNonspace()
+		p.addLine()
+	}
+}
+
+func (p *blockParser) continues(container *Node) continuation {
+	switch container.Type {
+	case BLOCKQUOTE:
+		if p.indented || peek(p.line, p.nextNonspace) != '>' {
+			return unmatched
+		}
+		p.advanceNextNonspace()
+		p.advanceOffset(1, false)
+		if isSpaceOrTab(peek(p.line, p.offset)) {
+			p.advanceOffset(1, true)
+		}
+	case ITEM:
+		switch {
+		case p.blank && container.FirstChild == nil:
+			// A list item can begin with at most one blank line.
+			return unmatched
+		case p.blank:
+			p.advanceNextNonspace()
+		case p.indent >= container.markerShift+container.padding:
+			p.advanceOffset(container.markerShift+container.padding, true)
+		default:
+			return unmatched
+		}
+	case HEADING, THEMATICBREAK:
+		return unmatched
+	case CODEBLOCK:
+		if container.Fenced {
+			if n := closingFence(p.line[p.nextNonspace:], container.fenceChar); p.indent <= 3 && n >= container.fenceLength {
+				p.finalize(container, p.position(p.nextNonspace+n))
+				return consumed
+			}
+			for i := container.fenceOffset; i > 0 && isSpaceOrTab(peek(p.line, p.offset)); i-- {
+				p.advanceOffset(1, true)
+			}
+			return matched
+		}
+		switch {
+		case p.indent >= codeIndent:
+			p.advanceOffset(codeIndent, true)
+		case p.blank:
+			p.advanceNextNonspace()
+		default:
+			return unmatched
+		}
+	case HTMLBLOCK:
+		if p.blank && (container.htmlType == 6 || container.htmlType == 7) {
+			return unmatched
+		}
+	case PARAGRAPH:
+		if p.blank {
+			return unmatched
+		}
+	}
+	return matched
+}
+
+var blockStarts = []func(p *blockParser, container *Node) start{
+	(*blockParser).startBlockQuote,
+	(*blockParser).startATXHeading,
+	(*blockParser).startFencedCode,
+	(*blockParser).startHTMLBlock,
+	(*blockParser).startSetextHeading,
+	(*blockParser).startThematicBreak,
+	(*blockParser).startListItem,
+	(*blockParser).startIndentedCode,
+}
+
+func (p *blockParser) startBlockQuote(container *Node) start {
+	if p.indented || peek(p.line, p.nextNonspace) != '>' {
+		return noStart
+	}
+	p.advanceNextNonspace()
+	p.advanceOffset(1, false)
+	if isSpaceOrTab(peek(p.line, p.offset)) {
+		p.advanceOffset(1, true)
+	}
+	p.closeUnmatchedBlocks()
+	p.addChild(BLOCKQUOTE, p.nextNonspace)
+	return containerStart
+}
+
+var (
+	atxHeadingMarker = regexp.MustCompile(`^#{1,6}(?:[ \t]+|$)`)
+	atxEmptyClosing  = regexp.MustCompile(`^[ \t]*#+[ \t]*$`)
+	atxClosing       = regexp.MustCompile(`[ \t]+#+[ \t]*$`)
+	setextUnderline  = regexp.MustCompile(`^(?:=+|-+)[ \t]*$`)
+)
+
+func (p *blockParser) startATXHeading(container *Node) start {
+	marker := atxHeadingMarker.FindString(p.line[p.nextNonspace:])
+	if p.indented || marker == "" {
+		return noStart
+	}
+	p.advanceNextNonspace()
+	p.advanceOffset(len(marker), false)
+	p.closeUnmatchedBlocks()
+
+	heading := p.addChild(HEADING, p.nextNonspace)
+	heading.Level = len(strings.TrimRight(marker, " \t"))
+	text := atxEmptyClosing.ReplaceAllString(p.line[p.offset:], "")
+	text = atxClosing.ReplaceAllString(text, "")
+	heading.segments = []segment{p.segment(0, p.offset)}
+	heading.content = []byte(text)
+	p.advanceOffset(len(p.line)-p.offset, false)
+	return leafStart
+}
+
+func (p *blockParser) startFencedCode(container *Node) start {
+	rest := p.line[p.nextNonspace:]
+	n, ch := run(rest)
+	if p.indented || n < 3 || ch != '`' && ch != '~' || ch == '`' && strings.Contains(rest[n:], "`") {
+		return noStart
+	}
+	p.closeUnmatchedBlocks()
+	code := p.addChild(CODEBLOCK, p.nextNonspace)
+	code.Fenced = true
+	code.fenceLength = n
+	code.fenceChar = ch
+	code.fenceOffset = p.indent
+	p.advanceNextNonspace()
+	p.advanceOffset(n, false)
+	return leafStart
+}
+
+func (p *blockParser) startHTMLBlock(container *Node) start {
+	if p.indented || peek(p.line, p.nextNonspace) != '<' {
+		return noStart
+	}
+	rest := p.line[p.nextNonspace:]
+	lazy := !p.allClosed && !p.blank && p.tip.Type == PARAGRAPH
+	for htmlType := 1; htmlType <= 7; htmlType++ {
+		if !htmlBlockOpen[htmlType].MatchString(rest) {
+			continue
+		}
+		// Only the first six kinds of HTML blocks can interrupt a paragraph.
+		if htmlType == 7 && (container.Type == PARAGRAPH || lazy) {
+			continue
+		}
+		p.closeUnmatchedBlocks()
+		// The indentation is part of the HTML block.
+		html := p.addChild(HTMLBLOCK, p.offset)
+		html.htmlType = htmlType
+		return leafStart
+	}
+	return noStart
+}
+
+func (p *blockParser) startSetextHeading(container *Node) start {
+	if p.indented || container.Type != PARAGRAPH {
+		return noStart
+	}
+	underline := setextUnderline.FindString(p.line[p.nextNonspace:])
+	if underline == "" {
+		return noStart
+	}
+	p.closeUnmatchedBlocks()
+	p.parseReferences(container)
+	if isBlank(string(container.content[container.base:])) {
+		return noStart
+	}
+
+	heading := newNode(HEADING)
+	heading.open = true
+	heading.Range.Start = container.position(container.base)
+	heading.Level = 2
+	if underline[0] == '=' {
+		heading.Level = 1
+	}
+	heading.content = container.content
+	heading.segments = container.segments
+	heading.base = container.base
+	container.InsertAfter(heading)
+	container.Unlink()
+	p.tip = heading
+	p.advanceOffset(len(p.line)-p.offset, false)
+	return leafStart
+}
+
+func (p *blockParser) startThematicBreak(container *Node) start {
+	if p.indented || !isThematicBreak(p.line[p.nextNonspace:]) {
+		return noStart
+	}
+	p.closeUnmatchedBlocks()
+	p.addChild(THEMATICBREAK, p.nextNonspace)
+	p.advanceOffset(len(p.line)-p.offset, false)
+	return leafStart
+}
+
+func (p *blockParser) startListItem(container *Node) start {
+	if p.indented && container.Type != LIST {
+		return noStart
+	}
+	marker, ok := p.parseListMarker(container)
+	if !ok {
+		return noStart
+	}
+	p.closeUnmatchedBlocks()
+
+	if p.tip.Type != LIST || container.Ordered != marker.Ordered || container.Marker != marker.Marker {
+		list := p.addChild(LIST, p.nextNonspace)
+		list.Ordered, list.Start, list.Marker = marker.Ordered, marker.Start, marker.Marker
+		list.Tight = true
+	}
+	item := p.addChild(ITEM, p.nextNonspace)
+	item.Ordered, item.Start, item.Marker = marker.Ordered, marker.Start, marker.Marker
+	item.padding, item.markerShift = marker.padding, marker.markerShift
+	return containerStart
+}
+
+func (p *blockParser) startIndentedCode(container *Node) start {
+	if !p.indented || p.tip.Type == PARAGRAPH || p.blank {
+		return noStart
+	}
+	p.advanceOffset(codeIndent, true)
+	p.closeUnmatchedBlocks()
+	p.addChild(CODEBLOCK, p.offset)
+	return leafStart
+}
+
+// parseListMarker reads the list marker at the next non-space character and
+// the spaces after it, which together make up the item's indentation.
+func (p *blockParser) parseListMarker(container *Node) (*Node, bool) {
+	if p.indent >= codeIndent {
+		return nil, false
+	}
+	rest := p.line[p.nextNonspace:]
+	marker := &Node{Type: ITEM}
+	marker.markerShift = p.indent
+
+	var length int
+	switch {
+	case rest != "" && strings.IndexByte("*+-", rest[0]) >= 0:
+		marker.Marker = rest[:1]
+		length = 1
+	default:
+		digits := 0
+		for digits < len(rest) && digits < 10 && isDigit(rest[digits]) {
+			digits++
+		}
+		if digits == 0 || digits > 9 || digits == len(rest) || rest[digits] != '.' && rest[digits] != ')' {
+			return nil, false
+		}
+		start, _ := strconv.Atoi(rest[:digits])
+		// Only lists starting at 1 can interrupt a paragraph.
+		if container.Type == PARAGRAPH && start != 1 {
+			return nil, false
+		}
+		marker.Ordered, marker.Start, marker.Marker = true, start, rest[digits:digits+1]
+		length = digits + 1
+	}
+
+	if length < len(rest) && !isSpaceOrTab(rest[length]) {
+		return nil, false
+	}
+	// An empty list item cannot interrupt a paragraph.
+	if container.Type == PARAGRAPH && isBlank(rest[length:]) {
+		return nil, false
+	}
+
+	p.advanceNextNonspace()
+	p.advanceOffset(length, true)
+	spacesStartColumn, spacesStartOffset := p.column, p.offset
+	for {
+		p.advanceOffset(1, true)
+		if p.column-spacesStartColumn >= 5 || !isSpaceOrTab(peek(p.line, p.offset)) {
+			break
+		}
+	}
+	blankItem := p.offset >= len(p.line)
+	spaces := p.column - spacesStartColumn
+	if spaces >= 5 || spaces < 1 || blankItem {
+		// The content is indented like a code block, so the item's own
+		// indentation is just one space.
+		marker.padding = length + 1
+		p.column, p.offset = spacesStartColumn, spacesStartOffset
+		if isSpaceOrTab(peek(p.line, p.offset)) {
+			p.advanceOffset(1, true)
+		}
+	} else {
+		marker.padding = length + spaces
+	}
+	return marker, true
+}
+
+func (p *blockParser) closeUnmatchedBlocks() {
+	if p.allClosed {
+		return
+	}
+	for p.oldTip != p.lastMatched {
+		parent := p.oldTip.Parent
+		p.finalize(p.oldTip, p.prevEnd())
+		p.oldTip = parent
+	}
+	p.allClosed = true
+}
+
+func (p *blockParser) addChild(nodeType NodeType, offset int) *Node {
+	for !canContain(p.tip.Type, nodeType) {
+		p.finalize(p.tip, p.prevEnd())
+	}
+	child := newNode(nodeType)
+	child.open = true
+	child.Range.Start = p.position(offset)
+	p.tip.AppendChild(child)
+	p.tip = child
+	return child
+}
+
+func (p *blockParser) addLine() {
+	tip := p.tip
+	if p.partiallyConsumedTab {
+		// The rest of a tab that only partly belonged to the block's
+		// indentation is added as spaces.
+		tip.segments = append(tip.segments, p.segment(len(tip.content), p.offset))
+		tip.content = append(tip.content, strings.Repeat(" ", 4-p.column%4)...)
+		p.offset++
+	}
+	tip.segments = append(tip.segments, p.segment(len(tip.content), p.offset))
+	tip.content = append(tip.content, p.line[p.offset:]...)
+	tip.content = append(tip.content, '\n')
+}
+
+func (p *blockParser) segment(index int, column int) segment {
+	return segment{index: index, line: p.lineNr, column: column, offset: p.lineStart + column}
+}
+
+func (p *blockParser) finalize(b *Node, end Position) {
+	parent := b.Parent
+	b.open = false
+	b.Range.End = end
+
+	switch b.Type {
+	case PARAGRAPH:
+		p.parseReferences(b)
+		if isBlank(string(b.content[b.base:])) {
+			b.Unlink()
+		} else if b.base > 0 {
+			b.Range.Start = b.position(b.base)
+		}
+	case CODEBLOCK:
+		content := string(b.content)
+		if b.Fenced {
+			info, rest, _ := strings.Cut(content, "\n")
+			b.Info = unescapeString(strings.Trim(info, " \t"))
+			b.Literal = rest
+			break
+		}
+		lines := strings.Split(content, "\n")
+		for len(lines) > 0 && isBlank(lines[len(lines)-1]) {
+			lines = lines[:len(lines)-1]
+		}
+		b.Literal = strings.Join(lines, "\n") + "\n"
+		b.Range.End = b.position(len(b.Literal) - 1)
+	case HTMLBLOCK:
+		b.Literal = strings.TrimSuffix(string(b.content), "\n")
+	case ITEM:
+		if b.LastChild != nil {
+			b.Range.End = b.LastChild.Range.End
+		} else {
+			b.Range.End = b.Range.Start
+			b.Range.End.Column += b.padding - 1
+			b.Range.End.Offset += b.padding - 1
+		}
+	case LIST:
+		b.Tight = isTight(b)
+		b.Range.End = b.LastChild.Range.End
+	}
+	if b.Type != PARAGRAPH && b.Type != HEADING {
+		b.content, b.segments = nil, nil
+	}
+	p.tip = parent
+}
+
+func (p *blockParser) parseReferences(b *Node) {
+	content := string(b.content)
+	for b.base < len(content) && content[b.base] == '[' {
+		n := parseReference(content[b.base:], p.refs)
+		if n == 0 {
+			break
+		}
+		b.base += n
+	}
+}
+
+// isTight reports whether no blank line separates the items of the list or
+// the blocks directly inside them.
+func isTight(list *Node) bool {
+	for item := list.FirstChild; item != nil; item = item.Next {
+		if item.Next != nil && endsWithBlankLine(item) {
+			return false
+		}
+		for child := item.FirstChild; child != nil; child = child.Next {
+			if (item.Next != nil || child.Next != nil) && endsWithBlankLine(child) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func endsWithBlankLine(b *Node) bool {
+	return b.Next != nil && b.Range.End.Line != b.Next.Range.Start.Line-1
+}
+
+func (p *blockParser) finish() *Node {
+	for p.tip != nil {
+		p.finalize(p.tip, p.prevEnd())
+	}
+	Walk(p.doc, func(n *Node, entering bool) bool {
+		if n.Type == PARAGRAPH || n.Type == HEADING {
+			parseInlines(n, p.refs)
+			n.content, n.segments = nil, nil
+			return false
+		}
+		return true
+	})
+	Walk(p.doc, func(n *Node, entering bool) bool {
+		if entering {
+			replaceNUL(n)
+		}
+		return true
+	})
+	return p.doc
+}
+
+// replaceNUL replaces the NUL characters of the node's values with U+FFFD as
+// the spec requires. The lines keep them while parsing so that positions stay
+// byte offsets of the source; the inline parser reads NUL like U+FFFD.
+func replaceNUL(n *Node) {
+	for _, value := range []*string{&n.Literal, &n.Info, &n.Destination, &n.Title} {
+		if strings.IndexByte(*value, 0) >= 0 {
+			*value = strings.ReplaceAll(*value, "\x00", "\uFFFD")
+		}
+	}
+}
+
+func (p *blockParser) findNextNonspace() {
+	i, column := p.offset, p.column
+	for ; i < len(p.line); i++ {
+		if c := p.line[i]; c == ' ' {
+			column++
+		} else if c == '\t' {
+			column += 4 - column%4
+		} else {
+			break
+		}
+	}
+	p.blank = i == len(p.line)
+	p.nextNonspace, p.nextNonspaceColumn = i, column
+	p.indent = column - p.column
+	p.indented = p.indent >= codeIndent
+}
+
+func (p *blockParser) advanceNextNonspace() {
+	p.offset, p.column = p.nextNonspace, p.nextNonspaceColumn
+	p.partiallyConsumedTab = false
+}
+
+// advanceOffset moves over count characters, or count columns of them, where
+// a tab may then be consumed only partly.
+func (p *blockParser) advanceOffset(count int, columns bool) {
+	for count > 0 && p.offset < len(p.line) {
+		if p.line[p.offset] != '\t' {
+			p.partiallyConsumedTab = false
+			p.offset++
+			p.column++
+			count--
+			continue
+		}
+		toTab := 4 - p.column%4
+		if !columns {
+			p.partiallyConsumedTab = false
+			p.column += toTab
+			p.offset++
+			count--
+			continue
+		}
+		p.partiallyConsumedTab = toTab > count
+		if p.partiallyConsumedTab {
+			toTab = count
+		} else {
+			p.offset++
+		}
+		p.column += toTab
+		count -= toTab
+	}
+}
+
+func (p *blockParser) position(offset int) Position {
+	return Position{Line: p.lineNr, Column: offset + 1, Offset: p.lineStart + offset}
+}
+
+func (p *blockParser) prevEnd() Position {
+	return Position{Line: p.prev.nr, Column: p.prev.length + 1, Offset: p.prev.start + p.prev.length}
+}
+
+func canContain(parent NodeType, child NodeType) bool {
+	switch parent {
+	case DOCUMENT, BLOCKQUOTE, ITEM:
+		return child != ITEM
+	case LIST:
+		return child == ITEM
+	}
+	return false
+}
+
+func acceptsLines(nodeType NodeType) bool {
+	return nodeType == PARAGRAPH || nodeType == CODEBLOCK || nodeType == HTMLBLOCK
+}
+
+// maySpecial reports whether a block other than a paragraph can start with
+// the text.
+func maySpecial(text string) bool {
+	return text != "" && strings.IndexByte("#`~*+_=<>0123456789-", text[0]) >= 0
+}
+
+func isThematicBreak(text string) bool {
+	if text == "" || strings.IndexByte("*-_", text[0]) < 0 {
+		return false
+	}
+	count := 0
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case text[0]:
+			count++
+		case ' ', '\t':
+		default:
+			return false
+		}
+	}
+	return count >= 3
+}
+
+// closingFence returns the length of the fence that closes a code block
+// fenced with ch, or 0 if the text is none.
+func closingFence(text string, ch byte) int {
+	n, c := run(text)
+	if n < 3 || c != ch || !isBlank(text[n:]) {
+		return 0
+	}
+	return n
+}
+
+// run returns the length and character of the run the text starts with.
+func run(text string) (int, byte) {
+	if text == "" {
+		return 0, 0
+	}
+	n := 1
+	for n < len(text) && text[n] == text[0] {
+		n++
+	}
+	return n, text[0]
+}
+
+func peek(text string, i int) byte {
+	if i < len(text) {
+		return text[i]
+	}
+	return 0
+}
+
+func isSpaceOrTab(c byte) bool {
+	return c == ' ' || c == '\t'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isBlank(text string) bool {
+	return strings.Trim(text, " \t\r\n") == ""
+}
+
+var (
+	htmlBlockOpen = []*regexp.Regexp{
+		nil,
+		regexp.MustCompile(`(?i)^<(?:script|pre|textarea|style)(?:\s|>|$)`),
+		regexp.MustCompile(`^<!--`),
+		regexp.MustCompile(`^<[?]`),
+		regexp.MustCompile(`^<![A-Za-z]`),
+		regexp.MustCompile(`^<!\[CDATA\[`),
+		regexp.MustCompile(`(?i)^</?(?:address|article|aside|base|basefont|blockquote|body|caption|center|col|colgroup|dd|details|dialog|dir|div|dl|dt|fieldset|figcaption|figure|footer|form|frame|frameset|h[123456]|head|header|hr|html|iframe|legend|li|link|main|menu|menuitem|nav|noframes|ol|optgroup|option|p|param|search|section|summary|table|tbody|td|tfoot|th|thead|title|tr|track|ul)(?:\s|/?>|$)`),
+		regexp.MustCompile(`(?i)^(?:` + openTag + `|` + closeTag + `)\s*$`),
+	}
+	htmlBlockClose = []*regexp.Regexp{
+		nil,
+		regexp.MustCompile(`(?i)</(?:script|pre|textarea|style)>`),
+		regexp.MustCompile(`-->`),
+		regexp.MustCompile(`\?>`),
+		regexp.MustCompile(`>`),
+		regexp.MustCompile(`\]\]>`),
+	}
+)
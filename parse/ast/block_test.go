@@ -0,0 +1,139 @@
+package ast
+
+import "testing"
+
+func TestParseShouldReturnBlocks(t *testing.T) {
+	tests := map[string]struct {
+		input    string
+		expected string
+	}{
+		"Paragraphs":        {"a\nb\n\nc", "Document[Paragraph[Text(a) SoftBreak Text(b)] Paragraph[Text(c)]]"},
+		"ATXHeading":        {"## a ##", "Document[Heading[Text(a)]]"},
+		"SetextHeading":     {"a\nb\n===", "Document[Heading[Text(a) SoftBreak Text(b)]]"},
+		"ThematicBreak":     {"a\n\n* * *", "Document[Paragraph[Text(a)] ThematicBreak]"},
+		"SetextOverBreak":   {"a\n---", "Document[Heading[Text(a)]]"},
+		"BlockQuote":        {"> a\n> # b", "Document[BlockQuote[Paragraph[Text(a)] Heading[Text(b)]]]"},
+		"LazyContinuation":  {"> a\nb", "Document[BlockQuote[Paragraph[Text(a) SoftBreak Text(b)]]]"},
+		"NestedBlockQuote":  {"> > a", "Document[BlockQuote[BlockQuote[Paragraph[Text(a)]]]]"},
+		"BulletList":        {"- a\n- b", "Document[List[Item[Paragraph[Text(a)]] Item[Paragraph[Text(b)]]]]"},
+		"ListChangesMarker": {"- a\n+ b", "Document[List[Item[Paragraph[Text(a)]]] List[Item[Paragraph[Text(b)]]]]"},
+		"NestedList":        {"1. a\n   - b", "Document[List[Item[Paragraph[Text(a)] List[Item[Paragraph[Text(b)]]]]]]"},
+		"EmptyItem":         {"-\n- a", "Document[List[Item Item[Paragraph[Text(a)]]]]"},
+		"ListInterrupts":    {"a\n- b", "Document[Paragraph[Text(a)] List[Item[Paragraph[Text(b)]]]]"},
+		"OrderedNeedsOne":   {"a\n2. b", "Document[Paragraph[Text(a) SoftBreak Text(2. b)]]"},
+		"IndentedCode":      {"    a\n\n    b\n\n", "Document[CodeBlock]"},
+		"CodeNoInterrupt":   {"a\n    b", "Document[Paragraph[Text(a) SoftBreak Text(b)]]"},
+		"FencedCode":        {"```\n# a\n```", "Document[CodeBlock]"},
+		"HTMLBlock":         {"<div>\n*a*\n</div>", "Document[HTMLBlock]"},
+		"HTMLBlockEnds":     {"<div>\n\n*a*", "Document[HTMLBlock Paragraph[Emphasis[Text(a)]]]"},
+		"Reference":         {"[a]: /u\n\n[a]", "Document[Paragraph[Link[Text(a)]]]"},
+		"ReferenceThenText": {"[a]: /u\nb", "Document[Paragraph[Text(b)]]"},
+		"Tabs":              {"-\ta\n\n\tb", "Document[List[Item[Paragraph[Text(a)] Paragraph[Text(b)]]]]"},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := dump(Parse(tc.input)); got != tc.expected {
+				t.Fatalf("Parse(%q) = %s, expected %s", tc.input, got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestParseShouldReturnListFields(t *testing.T) {
+	tests := map[string]struct {
+		input   string
+		ordered bool
+		start   int
+		marker  string
+		tight   bool
+	}{
+		"Bullet":         {"- a\n- b", false, 0, "-", true},
+		"Star":           {"* a", false, 0, "*", true},
+		"Ordered":        {"3. a\n4. b", true, 3, ".", true},
+		"Paren":          {"1) a", true, 1, ")", true},
+		"LooseItems":     {"- a\n\n- b", false, 0, "-", false},
+		"LooseContent":   {"- a\n\n  b", false, 0, "-", false},
+		"TightNested":    {"- a\n  - b\n\n  - c", false, 0, "-", true},
+		"TrailingBlank":  {"- a\n- b\n\n", false, 0, "-", true},
+		"BlankInFence":   {"- ```\n  a\n\n  ```\n- b", false, 0, "-", true},
+		"BlankAfterItem": {"- a\n-\n\n- b", false, 0, "-", false},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			list := Parse(tc.input).FirstChild
+			if list.Type != LIST {
+				t.Fatalf("Parse(%q) = %s, expected a list", tc.input, dump(list))
+			}
+			if list.Ordered != tc.ordered || list.Start != tc.start || list.Marker != tc.marker || list.Tight != tc.tight {
+				t.Fatalf("Parse(%q) = ordered %v start %d marker %q tight %v, expected %v %d %q %v",
+					tc.input, list.Ordered, list.Start, list.Marker, list.Tight, tc.ordered, tc.start, tc.marker, tc.tight)
+			}
+		})
+	}
+}
+
+func TestParseShouldReturnCodeBlockFields(t *testing.T) {
+	tests := map[string]struct {
+		input   string
+		fenced  bool
+		info    string
+		literal string
+	}{
+		"Indented":      {"    a\n      b\n\n", false, "", "a\n  b\n"},
+		"IndentedTab":   {"\ta\n", false, "", "a\n"},
+		"Fenced":        {"```\na\n```", true, "", "a\n"},
+		"Info":          {"~~~ go  run\na\n~~~", true, "go  run", "a\n"},
+		"InfoEscapes":   {"``` a\\_b&amp;\n```", true, "a_b&", ""},
+		"Unclosed":      {"```\na\n\nb", true, "", "a\n\nb\n"},
+		"FenceIndent":   {"  ```\n   a\n b\n  ```", true, "", " a\nb\n"},
+		"LongerClosing": {"```\na\n`````", true, "", "a\n"},
+		"ShorterFence":  {"````\na\n```\n````", true, "", "a\n```\n"},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			code := Parse(tc.input).FirstChild
+			if code.Type != CODEBLOCK {
+				t.Fatalf("Parse(%q) = %s, expected a code block", tc.input, dump(code))
+			}
+			if code.Fenced != tc.fenced || code.Info != tc.info || code.Literal != tc.literal {
+				t.Fatalf("Parse(%q) = fenced %v info %q literal %q, expected %v %q %q",
+					tc.input, code.Fenced, code.Info, code.Literal, tc.fenced, tc.info, tc.literal)
+			}
+		})
+	}
+}
+
+func TestParseShouldReturnBlockRanges(t *testing.T) {
+	input := "# Title\n\n> quote\n> more\n\n- a\n- b\n\n  c\n\n```go\ncode\n```\n    indented\n\n\n***\n"
+	doc := Parse(input)
+
+	tests := map[string]struct {
+		node  *Node
+		start Position
+		src   string
+	}{
+		"Heading":        {doc.FirstChild, Position{Line: 0, Column: 1, Offset: 0}, "# Title"},
+		"BlockQuote":     {doc.FirstChild.Next, Position{Line: 2, Column: 1, Offset: 9}, "> quote\n> more"},
+		"QuoteParagraph": {doc.FirstChild.Next.FirstChild, Position{Line: 2, Column: 3, Offset: 11}, "quote\n> more"},
+		"List":           {doc.FirstChild.Next.Next, Position{Line: 5, Column: 1, Offset: 25}, "- a\n- b\n\n  c"},
+		"SecondItem":     {doc.FirstChild.Next.Next.LastChild, Position{Line: 6, Column: 1, Offset: 29}, "- b\n\n  c"},
+		"FencedCode":     {doc.FirstChild.Next.Next.Next, Position{Line: 10, Column: 1, Offset: 39}, "```go\ncode\n```"},
+		"IndentedCode":   {doc.LastChild.Prev, Position{Line: 13, Column: 5, Offset: 58}, "indented"},
+		"ThematicBreak":  {doc.LastChild, Position{Line: 16, Column: 1, Offset: 69}, "***"},
+		"Document":       {doc, Position{Line: 0, Column: 1, Offset: 0}, input[:len(input)-1]},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if tc.node.Range.Start != tc.start {
+				t.Fatalf("Parse(%q) %s starts at %+v, expected %+v", input, name, tc.node.Range.Start, tc.start)
+			}
+			if got := span(input, tc.node.Range); got != tc.src {
+				t.Fatalf("Parse(%q) %s spans %q, expected %q", input, name, got, tc.src)
+			}
+		})
+	}
+}
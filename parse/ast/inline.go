@@ -0,0 +1,882 @@
+package ast
+
+import (
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Inlines are parsed from the text of a paragraph or heading in one pass.
+// Brackets and emphasis delimiters are kept on stacks as text nodes and only
+// turned into links and emphasis once their closers are found.
+
+const (
+	tagName            = `[A-Za-z][A-Za-z0-9-]*`
+	attributeName      = `[a-zA-Z_:][a-zA-Z0-9:._-]*`
+	unquotedValue      = "[^\"'=<>`\\x01-\\x20]+"
+	singleQuotedValue  = `'[^']*'`
+	doubleQuotedValue  = `"[^"]*"`
+	attributeValue     = `(?:` + unquotedValue + `|` + singleQuotedValue + `|` + doubleQuotedValue + `)`
+	attributeValueSpec = `(?:\s*=\s*` + attributeValue + `)`
+	attribute          = `(?:\s+` + attributeName + attributeValueSpec + `?)`
+	openTag            = `<` + tagName + attribute + `*\s*/?>`
+	closeTag           = `</` + tagName + `\s*>`
+)
+
+var (
+	htmlTag       = regexp.MustCompile(`^(?:` + openTag + `|` + closeTag + `)`)
+	emailAutolink = regexp.MustCompile("^<([a-zA-Z0-9.!#$%&'*+/=?^_`{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*)>")
+	uriAutolink   = regexp.MustCompile(`^<([A-Za-z][A-Za-z0-9.+-]{1,31}:[^<>\x01-\x20]*)>`)
+	entity        = regexp.MustCompile(`^&(?:#[xX][0-9a-fA-F]{1,6}|#[0-9]{1,7}|[A-Za-z][A-Za-z0-9]{1,31});`)
+)
+
+const maxLinkParenDepth = 32
+
+type reference struct {
+	destination string
+	title       string
+}
+
+type delimiter struct {
+	ch        byte
+	count     int
+	origCount int
+	node      *Node
+	prev      *delimiter
+	next      *delimiter
+	canOpen   bool
+	canClose  bool
+}
+
+type bracket struct {
+	node          *Node
+	prev          *bracket
+	prevDelimiter *delimiter
+	index         int
+	image         bool
+	active        bool
+	bracketAfter  bool
+}
+
+type inlineParser struct {
+	subject    string
+	pos        int
+	delimiters *delimiter
+	brackets   *bracket
+	refs       map[string]reference
+	missing    map[string]int
+	noCloser   map[int]bool
+}
+
+// parseInlines replaces the text content of a paragraph or heading with its
+// inline nodes.
+func parseInlines(b *Node, refs map[string]reference) {
+	content := string(b.content[b.base:])
+	subject := strings.TrimLeft(content, " \t\n")
+	base := b.base + len(content) - len(subject)
+	subject = strings.TrimRight(subject, " \t\n")
+
+	p := &inlineParser{subject: subject, refs: refs}
+	for p.pos < len(p.subject) {
+		p.parseInline(b)
+	}
+	p.processEmphasis(nil)
+
+	mergeText(b)
+	Walk(b, func(n *Node, entering bool) bool {
+		if entering && n != b {
+			n.Range = Range{Start: b.position(base + n.from), End: b.position(base + n.to)}
+		}
+		return true
+	})
+}
+
+func (p *inlineParser) parseInline(b *Node) {
+	var ok bool
+	switch p.subject[p.pos] {
+	case '\n':
+		ok = p.parseNewline(b)
+	case '\\':
+		ok = p.parseBackslash(b)
+	case '`':
+		ok = p.parseBackticks(b)
+	case '*', '_':
+		ok = p.handleDelim(b)
+	case '[':
+		ok = p.parseOpenBracket(b)
+	case '!':
+		ok = p.parseBang(b)
+	case ']':
+		ok = p.parseCloseBracket(b)
+	case '<':
+		ok = p.parseAutolink(b) || p.parseHTMLTag(b)
+	case '&':
+		ok = p.parseEntity(b)
+	default:
+		ok = p.parseString(b)
+	}
+	if !ok {
+		_, size := utf8.DecodeRuneInString(p.subject[p.pos:])
+		p.pos += size
+		b.AppendChild(p.text(p.subject[p.pos-size:p.pos], p.pos-size))
+	}
+}
+
+func (p *inlineParser) text(literal string, from int) *Node {
+	n := p.node(TEXT, from, from+len(literal))
+	n.Literal = literal
+	return n
+}
+
+func (p *inlineParser) node(nodeType NodeType, from, to int) *Node {
+	n := newNode(nodeType)
+	n.from, n.to = from, to
+	return n
+}
+
+func (p *inlineParser) peek() byte {
+	return peek(p.subject, p.pos)
+}
+
+func (p *inlineParser) parseString(b *Node) bool {
+	start := p.pos
+	for p.pos < len(p.subject) && strings.IndexByte("\n`[]\\!<&*_", p.subject[p.pos]) < 0 {
+		p.pos++
+	}
+	if p.pos == start {
+		return false
+	}
+	b.AppendChild(p.text(p.subject[start:p.pos], start))
+	return true
+}
+
+// parseNewline turns a line ending into a soft break, or a hard break if the
+// line ends in two or more spaces.
+func (p *inlineParser) parseNewline(b *Node) bool {
+	start := p.pos
+	p.pos++
+	breakType := SOFTBREAK
+	if last := b.LastChild; last != nil && last.Type == TEXT && strings.HasSuffix(last.Literal, " ") {
+		trimmed := strings.TrimRight(last.Literal, " ")
+		if len(last.Literal)-len(trimmed) >= 2 {
+			breakType = HARDBREAK
+		}
+		start -= len(last.Literal) - len(trimmed)
+		last.Literal = trimmed
+		last.to = start
+	}
+	b.AppendChild(p.node(breakType, start, p.pos))
+	for p.peek() == ' ' {
+		p.pos++
+	}
+	return true
+}
+
+func (p *inlineParser) parseBackslash(b *Node) bool {
+	p.pos++
+	switch c := p.peek(); {
+	case c == '\n':
+		p.pos++
+		b.AppendChild(p.node(HARDBREAK, p.pos-2, p.pos))
+	case isASCIIPunct(c):
+		p.pos++
+		b.AppendChild(p.text(p.subject[p.pos-1:p.pos], p.pos-2))
+		b.LastChild.to = p.pos
+	default:
+		b.AppendChild(p.text("\\", p.pos-1))
+	}
+	return true
+}
+
+// parseBackticks parses a code span, which ends at the next run of exactly
+// as many backticks as it starts with.
+func (p *inlineParser) parseBackticks(b *Node) bool {
+	start := p.pos
+	n, _ := run(p.subject[p.pos:])
+	p.pos += n
+	for i := p.pos; i < len(p.subject) && !p.noCloser[n]; {
+		if p.subject[i] != '`' {
+			i++
+			continue
+		}
+		m, _ := run(p.subject[i:])
+		if m != n {
+			i += m
+			continue
+		}
+		contents := strings.ReplaceAll(p.subject[p.pos:i], "\n", " ")
+		if strings.Trim(contents, " ") != "" && contents[0] == ' ' && contents[len(contents)-1] == ' ' {
+			contents = contents[1 : len(contents)-1]
+		}
+		code := p.node(CODE, start, i+m)
+		code.Literal = contents
+		b.AppendChild(code)
+		p.pos = i + m
+		return true
+	}
+	// No later run of backticks of this length can be closed either.
+	if p.noCloser == nil {
+		p.noCloser = map[int]bool{}
+	}
+	p.noCloser[n] = true
+	b.AppendChild(p.text(p.subject[start:p.pos], start))
+	return true
+}
+
+// scanDelims measures the run of delimiters at the current position and
+// whether it can open or close emphasis.
+func (p *inlineParser) scanDelims() (int, bool, bool) {
+	ch := p.peek()
+	n, _ := run(p.subject[p.pos:])
+
+	before, after := '\n', '\n'
+	if p.pos > 0 {
+		before, _ = utf8.DecodeLastRuneInString(p.subject[:p.pos])
+	}
+	if p.pos+n < len(p.subject) {
+		after, _ = utf8.DecodeRuneInString(p.subject[p.pos+n:])
+	}
+
+	afterSpace, afterPunct := isUnicodeSpace(after), isUnicodePunct(after)
+	beforeSpace, beforePunct := isUnicodeSpace(before), isUnicodePunct(before)
+	leftFlanking := !afterSpace && (!afterPunct || beforeSpace || beforePunct)
+	rightFlanking := !beforeSpace && (!beforePunct || afterSpace || afterPunct)
+
+	if ch == '_' {
+		return n, leftFlanking && (!rightFlanking || beforePunct), rightFlanking && (!leftFlanking || afterPunct)
+	}
+	return n, leftFlanking, rightFlanking
+}
+
+func (p *inlineParser) handleDelim(b *Node) bool {
+	ch := p.peek()
+	n, canOpen, canClose := p.scanDelims()
+	start := p.pos
+	p.pos += n
+	node := p.text(p.subject[start:p.pos], start)
+	b.AppendChild(node)
+
+	if canOpen || canClose {
+		p.delimiters = &delimiter{ch: ch, count: n, origCount: n, node: node, prev: p.delimiters, canOpen: canOpen, canClose: canClose}
+		if p.delimiters.prev != nil {
+			p.delimiters.prev.next = p.delimiters
+		}
+	}
+	return true
+}
+
+func (p *inlineParser) removeDelimiter(d *delimiter) {
+	if d.prev != nil {
+		d.prev.next = d.next
+	}
+	if d.next == nil {
+		p.delimiters = d.prev
+	} else {
+		d.next.prev = d.prev
+	}
+}
+
+// processEmphasis matches the emphasis delimiters above bottom, pairing every
+// closer with the nearest opener it can close.
+func (p *inlineParser) processEmphasis(bottom *delimiter) {
+	var openersBottom [12]*delimiter
+	for i := range openersBottom {
+		openersBottom[i] = bottom
+	}
+
+	closer := p.delimiters
+	for closer != nil && closer.prev != bottom {
+		closer = closer.prev
+	}
+
+	for closer != nil {
+		if !closer.canClose {
+			closer = closer.next
+			continue
+		}
+
+		bottomIndex := closer.origCount % 3
+		if closer.canOpen {
+			bottomIndex += 3
+		}
+		if closer.ch == '*' {
+			bottomIndex += 6
+		}
+
+		opener := closer.prev
+		found := false
+		for opener != nil && opener != bottom && opener != openersBottom[bottomIndex] {
+			// Delimiter runs that can both open and close only match if
+			// the sum of their lengths is not a multiple of three.
+			oddMatch := (closer.canOpen || opener.canClose) && closer.origCount%3 != 0 && (opener.origCount+closer.origCount)%3 == 0
+			if opener.ch == closer.ch && opener.canOpen && !oddMatch {
+				found = true
+				break
+			}
+			opener = opener.prev
+		}
+
+		if !found {
+			openersBottom[bottomIndex] = closer.prev
+			next := closer.next
+			if !closer.canOpen {
+				p.removeDelimiter(closer)
+			}
+			closer = next
+			continue
+		}
+
+		use := 1
+		if closer.count >= 2 && opener.count >= 2 {
+			use = 2
+		}
+		openerNode, closerNode := opener.node, closer.node
+		opener.count -= use
+		closer.count -= use
+		openerNode.Literal = openerNode.Literal[:len(openerNode.Literal)-use]
+		closerNode.Literal = closerNode.Literal[:len(closerNode.Literal)-use]
+		openerNode.to -= use
+		closerNode.from += use
+
+		emph := p.node(EMPHASIS, openerNode.to, closerNode.from)
+		if use == 2 {
+			emph.Type = STRONG
+		}
+		for child := openerNode.Next; child != nil && child != closerNode; {
+			next := child.Next
+			emph.AppendChild(child)
+			child = next
+		}
+		openerNode.InsertAfter(emph)
+
+		if opener.next != closer {
+			opener.next = closer
+			closer.prev = opener
+		}
+		if opener.count == 0 {
+			openerNode.Unlink()
+			p.removeDelimiter(opener)
+		}
+		if closer.count == 0 {
+			closerNode.Unlink()
+			next := closer.next
+			p.removeDelimiter(closer)
+			closer = next
+		}
+	}
+
+	for p.delimiters != nil && p.delimiters != bottom {
+		p.removeDelimiter(p.delimiters)
+	}
+}
+
+func (p *inlineParser) addBracket(node *Node, index int, image bool) {
+	if p.brackets != nil {
+		p.brackets.bracketAfter = true
+	}
+	p.brackets = &bracket{node: node, prev: p.brackets, prevDelimiter: p.delimiters, index: index, image: image, active: true}
+}
+
+func (p *inlineParser) parseOpenBracket(b *Node) bool {
+	node := p.text("[", p.pos)
+	b.AppendChild(node)
+	p.addBracket(node, p.pos, false)
+	p.pos++
+	return true
+}
+
+func (p *inlineParser) parseBang(b *Node) bool {
+	start := p.pos
+	p.pos++
+	if p.peek() != '[' {
+		b.AppendChild(p.text("!", start))
+		return true
+	}
+	p.pos++
+	node := p.text("![", start)
+	b.AppendChild(node)
+	p.addBracket(node, start+1, true)
+	return true
+}
+
+// parseCloseBracket turns the text since the last opening bracket into a
+// link or image if the bracket is followed by a destination or a label
+// matching a link reference definition.
+func (p *inlineParser) parseCloseBracket(b *Node) bool {
+	p.pos++
+	start := p.pos
+
+	opener := p.brackets
+	if opener == nil {
+		b.AppendChild(p.text("]", start-1))
+		return true
+	}
+	if !opener.active {
+		b.AppendChild(p.text("]", start-1))
+		p.brackets = opener.prev
+		return true
+	}
+
+	var destination, title string
+	found := false
+
+	if p.peek() == '(' {
+		p.pos++
+		p.spnl()
+		var ok bool
+		if destination, ok = p.parseLinkDestination(); ok {
+			p.spnl()
+			if isSpaceOrTab(peek(p.subject, p.pos-1)) || peek(p.subject, p.pos-1) == '\n' {
+				if t, ok := p.parseLinkTitle(); ok {
+					title = t
+				}
+			}
+			p.spnl()
+			found = p.peek() == ')'
+		}
+		if found {
+			p.pos++
+		} else {
+			p.pos = start
+		}
+	}
+
+	if !found {
+		beforeLabel := p.pos
+		n := p.parseLinkLabel()
+		var label string
+		switch {
+		case n > 2:
+			label = p.subject[beforeLabel : beforeLabel+n]
+		case !opener.bracketAfter:
+			// A collapsed or shortcut reference uses the link text as label.
+			label = p.subject[opener.index:start]
+		}
+		if n == 0 {
+			p.pos = start
+		}
+		if label != "" {
+			if ref, ok := p.refs[normalizeReference(label)]; ok {
+				destination, title = ref.destination, ref.title
+				found = true
+			}
+		}
+	}
+
+	if !found {
+		p.brackets = opener.prev
+		p.pos = start
+		b.AppendChild(p.text("]", start-1))
+		return true
+	}
+
+	link := p.node(LINK, opener.node.from, p.pos)
+	if opener.image {
+		link.Type = IMAGE
+	}
+	link.Destination, link.Title = destination, title
+	for child := opener.node.Next; child != nil; {
+		next := child.Next
+		link.AppendChild(child)
+		child = next
+	}
+	b.AppendChild(link)
+	p.processEmphasis(opener.prevDelimiter)
+	p.brackets = opener.prev
+	opener.node.Unlink()
+
+	// Links cannot contain other links.
+	if !opener.image {
+		for o := p.brackets; o != nil; o = o.prev {
+			if !o.image {
+				o.active = false
+			}
+		}
+	}
+	return true
+}
+
+// spnl skips spaces and tabs including up to one line ending.
+func (p *inlineParser) spnl() {
+	p.skipSpaces()
+	if p.peek() == '\n' {
+		p.pos++
+		p.skipSpaces()
+	}
+}
+
+func (p *inlineParser) skipSpaces() {
+	for isSpaceOrTab(p.peek()) {
+		p.pos++
+	}
+}
+
+func (p *inlineParser) parseLinkDestination() (string, bool) {
+	start := p.pos
+	if p.peek() == '<' {
+		for i := p.pos + 1; i < len(p.subject); i++ {
+			switch p.subject[i] {
+			case '\\':
+				if i+1 < len(p.subject) && p.subject[i+1] != '\n' {
+					i++
+				}
+			case '\n', '<':
+				return "", false
+			case '>':
+				p.pos = i + 1
+				return unescapeString(p.subject[start+1 : i]), true
+			}
+		}
+		return "", false
+	}
+
+	depth := 0
+loop:
+	for p.pos < len(p.subject) {
+		switch c := p.subject[p.pos]; {
+		case c == '\\' && isASCIIPunct(peek(p.subject, p.pos+1)):
+			p.pos += 2
+		case c == '(':
+			// Like the reference implementation, give up on deeply nested
+			// parentheses so that runs of openers cannot make every link
+			// rescan the rest of the text.
+			if depth++; depth > maxLinkParenDepth {
+				p.pos = start
+				return "", false
+			}
+			p.pos++
+		case c == ')':
+			if depth == 0 {
+				break loop
+			}
+			p.pos++
+			depth--
+		case c <= ' ' && c != 0 || c == 0x7f:
+			break loop
+		default:
+			p.pos++
+		}
+	}
+	if p.pos == start && p.peek() != ')' || depth != 0 {
+		p.pos = start
+		return "", false
+	}
+	return unescapeString(p.subject[start:p.pos]), true
+}
+
+func (p *inlineParser) parseLinkTitle() (string, bool) {
+	open := p.peek()
+	closing := open
+	switch open {
+	case '"', '\'':
+	case '(':
+		closing = ')'
+	default:
+		return "", false
+	}
+	for i := p.pos + 1; i < len(p.subject); i++ {
+		switch c := p.subject[i]; {
+		case c == '\\':
+			i++
+		case c == closing:
+			title := unescapeString(p.subject[p.pos+1 : i])
+			p.pos = i + 1
+			return title, true
+		case c == '(' && open == '(':
+			return "", false
+		}
+	}
+	return "", false
+}
+
+// parseLinkLabel returns the length of the link label at the current
+// position, or 0 if there is none.
+func (p *inlineParser) parseLinkLabel() int {
+	if p.peek() != '[' {
+		return 0
+	}
+	chars := 0
+	for i := p.pos + 1; i < len(p.subject); {
+		switch c := p.subject[i]; c {
+		case '\\':
+			i++
+			if i < len(p.subject) {
+				_, size := utf8.DecodeRuneInString(p.subject[i:])
+				i += size
+			}
+			chars += 2
+		case '[':
+			return 0
+		case ']':
+			if chars > 999 {
+				return 0
+			}
+			n := i + 1 - p.pos
+			p.pos = i + 1
+			return n
+		default:
+			_, size := utf8.DecodeRuneInString(p.subject[i:])
+			i += size
+			chars++
+		}
+		if chars > 999 {
+			return 0
+		}
+	}
+	return 0
+}
+
+func (p *inlineParser) parseAutolink(b *Node) bool {
+	start := p.pos
+	rest := p.subject[p.pos:]
+	destination := ""
+	var m []string
+	if m = emailAutolink.FindStringSubmatch(rest); m != nil {
+		destination = "mailto:" + m[1]
+	} else if m = uriAutolink.FindStringSubmatch(rest); m != nil {
+		destination = m[1]
+	} else {
+		return false
+	}
+	p.pos += len(m[0])
+	link := p.node(LINK, start, p.pos)
+	link.Destination = destination
+	link.AppendChild(p.text(m[1], start+1))
+	b.AppendChild(link)
+	return true
+}
+
+func (p *inlineParser) parseHTMLTag(b *Node) bool {
+	rest := p.subject[p.pos:]
+	end := -1
+	switch {
+	case strings.HasPrefix(rest, "<!-->"):
+		end = p.pos + len("<!-->")
+	case strings.HasPrefix(rest, "<!--->"):
+		end = p.pos + len("<!--->")
+	case strings.HasPrefix(rest, "<!--"):
+		end = p.scanTo(p.pos+len("<!--"), "-->")
+	case strings.HasPrefix(rest, "<?"):
+		end = p.scanTo(p.pos+len("<?"), "?>")
+	case strings.HasPrefix(rest, "<![CDATA["):
+		end = p.scanTo(p.pos+len("<![CDATA["), "]]>")
+	case strings.HasPrefix(rest, "<!") && len(rest) > 2 && isASCIILetter(rest[2]):
+		end = p.scanTo(p.pos+len("<!"), ">")
+	default:
+		if m := htmlTag.FindString(rest); m != "" {
+			end = p.pos + len(m)
+		}
+	}
+	if end < 0 {
+		return false
+	}
+	node := p.node(HTMLINLINE, p.pos, end)
+	node.Literal = p.subject[p.pos:end]
+	b.AppendChild(node)
+	p.pos = end
+	return true
+}
+
+// scanTo returns the index just past the first closer at or after from, or
+// -1. Once a closer is known to be missing from some index on, later scans
+// behind that index fail without searching again.
+func (p *inlineParser) scanTo(from int, closer string) int {
+	if at, ok := p.missing[closer]; ok && from >= at {
+		return -1
+	}
+	i := strings.Index(p.subject[from:], closer)
+	if i < 0 {
+		if p.missing == nil {
+			p.missing = map[string]int{}
+		}
+		p.missing[closer] = from
+		return -1
+	}
+	return from + i + len(closer)
+}
+
+func (p *inlineParser) parseEntity(b *Node) bool {
+	m := entity.FindString(p.subject[p.pos:])
+	decoded, ok := decodeEntity(m)
+	if !ok {
+		return false
+	}
+	b.AppendChild(p.text(decoded, p.pos))
+	p.pos += len(m)
+	b.LastChild.to = p.pos
+	return true
+}
+
+// parseReference parses a link reference definition at the start of s into
+// refs and returns its length, or 0 if s does not start with one.
+func parseReference(s string, refs map[string]reference) int {
+	p := &inlineParser{subject: s}
+
+	n := p.parseLinkLabel()
+	if n == 0 || p.peek() != ':' {
+		return 0
+	}
+	label := s[:n]
+	p.pos++
+
+	p.spnl()
+	destination, ok := p.parseLinkDestination()
+	if !ok {
+		return 0
+	}
+
+	beforeTitle := p.pos
+	p.spnl()
+	title, hasTitle := "", false
+	if p.pos != beforeTitle {
+		title, hasTitle = p.parseLinkTitle()
+	}
+	if !hasTitle {
+		p.pos = beforeTitle
+	}
+
+	// The definition has to end its line, possibly by dropping the title.
+	if !p.atLineEnd() {
+		if !hasTitle {
+			return 0
+		}
+		title = ""
+		p.pos = beforeTitle
+		if !p.atLineEnd() {
+			return 0
+		}
+	}
+
+	key := normalizeReference(label)
+	if key == "" {
+		return 0
+	}
+	if _, ok := refs[key]; !ok {
+		refs[key] = reference{destination: destination, title: title}
+	}
+	return p.pos
+}
+
+func (p *inlineParser) atLineEnd() bool {
+	p.skipSpaces()
+	switch p.peek() {
+	case '\n':
+		p.pos++
+		return true
+	case 0:
+		return p.pos >= len(p.subject)
+	}
+	return false
+}
+
+// normalizeReference case folds a link label, brackets included, and
+// collapses its whitespace so that matching labels compare equal.
+func normalizeReference(label string) string {
+	label = strings.TrimSpace(label[1 : len(label)-1])
+	label = strings.Join(strings.Fields(label), " ")
+	label = strings.ReplaceAll(strings.ToLower(label), "ß", "ss")
+	return strings.ToUpper(label)
+}
+
+// mergeText joins adjacent text nodes below n.
+func mergeText(n *Node) {
+	for child := n.FirstChild; child != nil; child = child.Next {
+		if child.Type != TEXT {
+			mergeText(child)
+			continue
+		}
+		if child.Next == nil || child.Next.Type != TEXT {
+			continue
+		}
+		var sb strings.Builder
+		sb.WriteString(child.Literal)
+		for child.Next != nil && child.Next.Type == TEXT {
+			sb.WriteString(child.Next.Literal)
+			child.to = child.Next.to
+			child.Next.Unlink()
+		}
+		child.Literal = sb.String()
+	}
+	for child := n.FirstChild; child != nil; {
+		next := child.Next
+		if child.Type == TEXT && child.Literal == "" {
+			child.Unlink()
+		}
+		child = next
+	}
+}
+
+// unescapeString resolves the backslash escapes and entities in s.
+func unescapeString(s string) string {
+	if !strings.ContainsAny(s, "\\&") {
+		return s
+	}
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\\' && i+1 < len(s) && isASCIIPunct(s[i+1]):
+			sb.WriteByte(s[i+1])
+			i++
+		case c == '&':
+			m := entity.FindString(s[i:])
+			if decoded, ok := decodeEntity(m); ok {
+				sb.WriteString(decoded)
+				i += len(m) - 1
+				continue
+			}
+			sb.WriteByte(c)
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String()
+}
+
+// decodeEntity decodes an entity or numeric character reference.
+func decodeEntity(m string) (string, bool) {
+	if m == "" {
+		return "", false
+	}
+	if m[1] == '#' {
+		var n uint64
+		var err error
+		if m[2] == 'x' || m[2] == 'X' {
+			n, err = strconv.ParseUint(m[3:len(m)-1], 16, 32)
+		} else {
+			n, err = strconv.ParseUint(m[2:len(m)-1], 10, 32)
+		}
+		r := rune(n)
+		if err != nil || n == 0 || !utf8.ValidRune(r) {
+			r = unicode.ReplacementChar
+		}
+		return string(r), true
+	}
+	// html.UnescapeString also knows entities that lack the semicolon and
+	// would decode a prefix of an unknown name, leaving the rest behind.
+	decoded := html.UnescapeString(m)
+	if decoded == m || strings.HasSuffix(decoded, ";") && decoded != ";" {
+		return "", false
+	}
+	return decoded, true
+}
+
+func isASCIIPunct(c byte) bool {
+	return c >= '!' && c <= '/' || c >= ':' && c <= '@' || c >= '[' && c <= '`' || c >= '{' && c <= '~'
+}
+
+func isASCIILetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isUnicodeSpace(r rune) bool {
+	return r == '\t' || r == '\n' || r == '\f' || r == '\r' || unicode.Is(unicode.Zs, r)
+}
+
+// NUL stands for U+FFFD, a symbol, in the character classes below and in the
+// patterns of the inline parser.
+func isUnicodePunct(r rune) bool {
+	return r == 0 || unicode.IsPunct(r) || unicode.IsSymbol(r)
+}
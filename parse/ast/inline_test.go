@@ -0,0 +1,202 @@
+package ast
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseShouldReturnInlines(t *testing.T) {
+	tests := map[string]struct {
+		input    string
+		expected string
+	}{
+		"Emphasis":         {"*a* _b_", "Emphasis[Text(a)] Text( ) Emphasis[Text(b)]"},
+		"Strong":           {"**a** __b__", "Strong[Text(a)] Text( ) Strong[Text(b)]"},
+		"Nested":           {"***a** b*", "Emphasis[Strong[Text(a)] Text( b)]"},
+		"IntrawordUnder":   {"snake_case_name", "Text(snake_case_name)"},
+		"IntrawordStar":    {"a*b*c", "Text(a) Emphasis[Text(b)] Text(c)"},
+		"RuleOfThree":      {"*a**b*", "Emphasis[Text(a**b)]"},
+		"Unmatched":        {"**a*", "Text(*) Emphasis[Text(a)]"},
+		"CodeSpan":         {"`a *b*`", "Code(a *b*)"},
+		"CodeSpanSpaces":   {"`` `a` ``", "Code(`a`)"},
+		"UnclosedCode":     {"``a`", "Text(``a`)"},
+		"Escapes":          {"\\*a\\* \\q", "Text(*a* \\q)"},
+		"Entities":         {"&amp; &#65; &#x42; &bogus;", "Text(& A B &bogus;)"},
+		"HardBreak":        {"a  \nb\\\nc", "Text(a) HardBreak Text(b) HardBreak Text(c)"},
+		"SoftBreak":        {"a \n b", "Text(a) SoftBreak Text(b)"},
+		"Link":             {"[a *b*](/u)", "Link[Text(a ) Emphasis[Text(b)]]"},
+		"Image":            {"![a](/u)", "Image[Text(a)]"},
+		"NoLinkInLink":     {"[a [b](/u)](/v)", "Text([a ) Link[Text(b)] Text(](/v))"},
+		"ImageInLink":      {"[![a](/i)](/u)", "Link[Image[Text(a)]]"},
+		"BracketsOnly":     {"[a] [b", "Text([a] [b)"},
+		"Autolink":         {"<https://x.y/z>", "Link[Text(https://x.y/z)]"},
+		"EmailAutolink":    {"<a@b.c>", "Link[Text(a@b.c)]"},
+		"InlineHTML":       {"a <b class=\"c\">d</b>", "Text(a ) HTMLInline(<b class=\"c\">) Text(d) HTMLInline(</b>)"},
+		"HTMLComment":      {"a <!-- b --> c", "Text(a ) HTMLInline(<!-- b -->) Text( c)"},
+		"NotHTML":          {"a < b", "Text(a < b)"},
+		"EmphasisAndLinks": {"*[a*](/u)", "Text(*) Link[Text(a*)]"},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			paragraph := Parse(tc.input).FirstChild
+			if got := dumpChildren(paragraph); got != tc.expected {
+				t.Fatalf("Parse(%q) = %s, expected %s", tc.input, got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestParseShouldReturnLinkFields(t *testing.T) {
+	tests := map[string]struct {
+		input       string
+		destination string
+		title       string
+	}{
+		"Plain":         {"[a](/u)", "/u", ""},
+		"Title":         {"[a](/u \"t\")", "/u", "t"},
+		"ParenTitle":    {"[a](/u (t))", "/u", "t"},
+		"AngleBrackets": {"[a](<my file> 't')", "my file", "t"},
+		"Escapes":       {"[a](/u\\)x \"a\\\"b\")", "/u)x", "a\"b"},
+		"Entities":      {"[a](/&ouml; \"&amp;\")", "/ö", "&"},
+		"Parens":        {"[a](/u(1)(2))", "/u(1)(2)", ""},
+		"Empty":         {"[a]()", "", ""},
+		"Full":          {"[a][B]\n\n[b]: /u \"t\"", "/u", "t"},
+		"Collapsed":     {"[a][]\n\n[a]: /u", "/u", ""},
+		"Shortcut":      {"[A  b]\n\n[a b]: /u", "/u", ""},
+		"FirstDefWins":  {"[a]\n\n[a]: /u\n[a]: /v", "/u", ""},
+		"CaseFold":      {"[ẞ]\n\n[SS]: /u", "/u", ""},
+		"Autolink":      {"<a@b.c>", "mailto:a@b.c", ""},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			link := Parse(tc.input).FirstChild.FirstChild
+			if link.Type != LINK {
+				t.Fatalf("Parse(%q) = %s, expected a link", tc.input, dump(link))
+			}
+			if link.Destination != tc.destination || link.Title != tc.title {
+				t.Fatalf("Parse(%q) = destination %q title %q, expected %q %q", tc.input, link.Destination, link.Title, tc.destination, tc.title)
+			}
+		})
+	}
+}
+
+func TestParseShouldReturnInlineRanges(t *testing.T) {
+	input := "> a *b*  \n> [c](/u)\n>\n> d `e`"
+	quote := Parse(input).FirstChild
+	first, second := quote.FirstChild, quote.LastChild
+
+	tests := map[string]struct {
+		node  *Node
+		start Position
+		src   string
+	}{
+		"Text":      {first.FirstChild, Position{Line: 0, Column: 3, Offset: 2}, "a "},
+		"Emphasis":  {first.FirstChild.Next, Position{Line: 0, Column: 5, Offset: 4}, "*b*"},
+		"Delimited": {first.FirstChild.Next.FirstChild, Position{Line: 0, Column: 6, Offset: 5}, "b"},
+		"HardBreak": {first.FirstChild.Next.Next, Position{Line: 0, Column: 8, Offset: 7}, "  \n> "},
+		"Link":      {first.LastChild, Position{Line: 1, Column: 3, Offset: 12}, "[c](/u)"},
+		"LinkText":  {first.LastChild.FirstChild, Position{Line: 1, Column: 4, Offset: 13}, "c"},
+		"Code":      {second.LastChild, Position{Line: 3, Column: 5, Offset: 26}, "`e`"},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if tc.node.Range.Start != tc.start {
+				t.Fatalf("Parse(%q) %s starts at %+v, expected %+v", input, name, tc.node.Range.Start, tc.start)
+			}
+			if got := span(input, tc.node.Range); got != tc.src {
+				t.Fatalf("Parse(%q) %s spans %q, expected %q", input, name, got, tc.src)
+			}
+		})
+	}
+}
+
+func TestParseShouldReturnByteRangesOfInvalidUTF8(t *testing.T) {
+	input := "\xff\xfe *x*\n\x00 `\xc3`"
+	doc := Parse(input)
+	first, second := doc.FirstChild.FirstChild.Next, doc.FirstChild.LastChild
+
+	if first.Type != EMPHASIS || first.Range.Start != (Position{Line: 0, Column: 4, Offset: 3}) || span(input, first.Range) != "*x*" {
+		t.Fatalf("Parse(%q) returned %s at %+v, expected Emphasis at offset 3", input, first.Type, first.Range)
+	}
+	if second.Type != CODE || second.Range.Start != (Position{Line: 1, Column: 3, Offset: 9}) || span(input, second.Range) != "`\xc3`" {
+		t.Fatalf("Parse(%q) returned %s at %+v, expected Code at offset 9", input, second.Type, second.Range)
+	}
+}
+
+func TestParseShouldReplaceNUL(t *testing.T) {
+	tests := map[string]string{
+		"a\x00b":          "Document[Paragraph[Text(a\uFFFDb)]]",
+		"*\x00*":          "Document[Paragraph[Emphasis[Text(\uFFFD)]]]",
+		"- \x00":          "Document[List[Item[Paragraph[Text(\uFFFD)]]]]",
+		"<http://a\x00b>": "Document[Paragraph[Link[Text(http://a\uFFFDb)]]]",
+	}
+	for input, expected := range tests {
+		if got := dump(Parse(input)); got != expected {
+			t.Errorf("Parse(%q) returned %s, expected %s", input, got, expected)
+		}
+	}
+
+	input := "[a](/\x00 \"\x00\")\n\n```\x00\n\x00\n```"
+	doc := Parse(input)
+	link, code := doc.FirstChild.FirstChild, doc.LastChild
+	if link.Type != LINK || link.Destination != "/\uFFFD" || link.Title != "\uFFFD" {
+		t.Errorf("Parse(%q) returned %s to %q titled %q, expected a link to %q", input, link.Type, link.Destination, link.Title, "/\uFFFD")
+	}
+	if code.Info != "\uFFFD" || code.Literal != "\uFFFD\n" {
+		t.Errorf("Parse(%q) returned code %q with info %q, expected %q", input, code.Literal, code.Info, "\uFFFD\n")
+	}
+}
+
+func TestParseShouldHandlePathologicalInput(t *testing.T) {
+	tests := map[string]string{
+		"NestedBrackets":    strings.Repeat("[", 20000) + "a" + strings.Repeat("]", 20000),
+		"OpenBrackets":      strings.Repeat("[a", 20000),
+		"OpenImages":        strings.Repeat("![a", 20000),
+		"NestedLinks":       strings.Repeat("[", 10000) + strings.Repeat("](b)", 10000),
+		"Delimiters":        strings.Repeat("*a _a ", 20000),
+		"NestedEmphasis":    strings.Repeat("*", 20000) + "a" + strings.Repeat("*", 20000),
+		"BacktickRuns":      backtickRuns(300),
+		"OpenDestinations":  strings.Repeat("[a](", 20000),
+		"OpenAngles":        strings.Repeat("[a](<", 20000),
+		"OpenTitles":        strings.Repeat("[a](b \"", 20000),
+		"OpenCDATA":         strings.Repeat("a <![CDATA[", 20000),
+		"OpenInstructions":  strings.Repeat("a <?", 20000),
+		"OpenDeclarations":  strings.Repeat("a <!A", 20000),
+		"OpenTags":          strings.Repeat("<a b=\"", 20000),
+		"Entities":          strings.Repeat("&a", 20000),
+		"References":        strings.Repeat("[a]: b\n", 10000) + strings.Repeat("[a] ", 10000),
+		"NestedLists":       strings.Repeat("- ", 5000) + "a",
+		"NestedBlockQuotes": strings.Repeat(">", 20000) + "a",
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			begin := time.Now()
+			Parse(input)
+			if elapsed := time.Since(begin); elapsed > 5*time.Second {
+				t.Fatalf("Parse of %s took %v", name, elapsed)
+			}
+		})
+	}
+}
+
+func dumpChildren(n *Node) string {
+	parts := []string{}
+	for _, child := range n.Children() {
+		parts = append(parts, dump(child))
+	}
+	return strings.Join(parts, " ")
+}
+
+// backtickRuns returns runs of one to n backticks, none of which is closed.
+func backtickRuns(n int) string {
+	var sb strings.Builder
+	for i := 1; i <= n; i++ {
+		sb.WriteString(strings.Repeat("`", i) + "a")
+	}
+	return sb.String()
+}
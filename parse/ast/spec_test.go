@@ -0,0 +1,35 @@
+package ast_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/siasmey/markdown/internal/commonmark"
+	"github.com/siasmey/markdown/parse/ast"
+	"github.com/siasmey/markdown/render/html"
+)
+
+func TestParseShouldPassSpecExamples(t *testing.T) {
+	examples, err := commonmark.Examples()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The spec's HTML is plain CommonMark with its raw HTML, without the
+	// renderer's extensions.
+	r := &html.Renderer{Unsafe: true}
+	report := commonmark.NewReport()
+	for _, ex := range examples {
+		var sb strings.Builder
+		if err := r.Render(&sb, ast.Parse(ex.Markdown)); err != nil {
+			t.Fatal(err)
+		}
+		got := sb.String()
+		passed := commonmark.NormalizeHTML(got) == commonmark.NormalizeHTML(ex.HTML)
+		report.Add(ex, passed)
+		if !passed {
+			t.Errorf("%s example %d: Parse(%q) rendered %q, expected %q", ex.Section, ex.Example, ex.Markdown, got, ex.HTML)
+		}
+	}
+	t.Logf("CommonMark %s examples passed by section:\n%s", commonmark.Version, report)
+}
@@ -0,0 +1,122 @@
+package ast
+
+// Splice puts inline nodes found in source by another parser, such as the
+// wikilinks and tags of package symbols, into the tree parsed from source.
+// Every node replaces the inlines spanning the source bytes from the Offset of
+// its Range start to that of its end, and text nodes are split where it
+// starts or ends inside them. The nodes are expected in source order; Splice
+// fills in the rest of their ranges.
+//
+// A node is left out if it overlaps the node before it, starts or ends inside
+// an inline that is not text, or lies outside of paragraphs and headings.
+func Splice(doc *Node, source string, nodes []*Node) {
+	end := 0
+	Walk(doc, func(b *Node, entering bool) bool {
+		if len(nodes) == 0 {
+			return false
+		}
+		if b.Type != PARAGRAPH && b.Type != HEADING {
+			return true
+		}
+
+		for len(nodes) > 0 && nodes[0].Range.Start.Offset < b.Range.Start.Offset {
+			nodes = nodes[1:]
+		}
+		var last *Node
+		for len(nodes) > 0 && nodes[0].Range.End.Offset <= b.Range.End.Offset {
+			n := nodes[0]
+			nodes = nodes[1:]
+			if n.Range.Start.Offset >= end && splice(b, last, source, n) {
+				last, end = n, n.Range.End.Offset
+			}
+		}
+		return false
+	})
+}
+
+// splice puts n in place of the inlines of block b it spans. Searches start
+// at hint, the node spliced into b before.
+func splice(b *Node, hint *Node, source string, n *Node) bool {
+	from, to := n.Range.Start.Offset, n.Range.End.Offset
+	parent := b
+	first := childAt(parent, hint, from)
+	for first != nil && isInlineContainer(first) && first.Range.Start.Offset <= from && to <= first.Range.End.Offset &&
+		(first.Range.Start.Offset != from || first.Range.End.Offset != to) {
+		parent = first
+		first = childAt(parent, hint, from)
+	}
+	if first == nil || first.Range.Start.Offset > from || first.Range.Start.Offset < from && first.Type != TEXT {
+		return false
+	}
+
+	last := first
+	for last.Next != nil && last.Next.Range.Start.Offset < to {
+		last = last.Next
+	}
+	if last.Range.End.Offset < to || last.Range.End.Offset > to && last.Type != TEXT {
+		return false
+	}
+
+	n.Range.Start, n.Range.End = first.Range.Start, last.Range.End
+	if first.Range.Start.Offset < from {
+		n.Range.Start = shift(first.Range.Start, from)
+	}
+	if last.Range.End.Offset > to {
+		n.Range.End = shift(last.Range.End, to)
+	}
+
+	replaced := []*Node{}
+	for child := first; child != last.Next; child = child.Next {
+		replaced = append(replaced, child)
+	}
+	if last.Range.End.Offset > to {
+		last.InsertAfter(textNode(source, n.Range.End, last.Range.End))
+	}
+	last.InsertAfter(n)
+	if first.Range.Start.Offset < from {
+		last.InsertAfter(textNode(source, first.Range.Start, n.Range.Start))
+	}
+	for _, child := range replaced {
+		child.Unlink()
+	}
+	return true
+}
+
+// childAt returns the first child of parent that ends after offset. The
+// search starts at the ancestor of hint below parent, if there is one.
+func childAt(parent *Node, hint *Node, offset int) *Node {
+	child := parent.FirstChild
+	for h := hint; h != nil; h = h.Parent {
+		if h.Parent == parent {
+			child = h
+			break
+		}
+	}
+	for child != nil && child.Range.End.Offset <= offset {
+		child = child.Next
+	}
+	return child
+}
+
+func isInlineContainer(n *Node) bool {
+	switch n.Type {
+	case EMPHASIS, STRONG, LINK, IMAGE:
+		return true
+	}
+	return false
+}
+
+// shift moves a position within the line of a text node to offset.
+func shift(pos Position, offset int) Position {
+	return Position{Line: pos.Line, Column: pos.Column + offset - pos.Offset, Offset: offset}
+}
+
+// textNode returns a text node for the source between start and end, which
+// lie on one line.
+func textNode(source string, start Position, end Position) *Node {
+	n := newNode(TEXT)
+	n.Literal = unescapeString(source[start.Offset:end.Offset])
+	n.Range = Range{Start: start, End: end}
+	replaceNUL(n)
+	return n
+}
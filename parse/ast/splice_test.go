@@ -0,0 +1,82 @@
+package ast
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSpliceShouldReplaceInlines(t *testing.T) {
+	tests := map[string]struct {
+		input    string
+		spans    [][2]int
+		expected string
+	}{
+		"WholeText":     {"#a", [][2]int{{0, 2}}, "Tag(#a)"},
+		"SplitText":     {"x #a y", [][2]int{{2, 4}}, "Text(x ) Tag(#a) Text( y)"},
+		"TwoInText":     {"#a #b", [][2]int{{0, 2}, {3, 5}}, "Tag(#a) Text( ) Tag(#b)"},
+		"AcrossNodes":   {"[[a *b* c]]", [][2]int{{0, 11}}, "Tag([[a *b* c]])"},
+		"OverLink":      {"[[P]]\n\n[P]: /u", [][2]int{{0, 5}}, "Tag([[P]])"},
+		"InEmphasis":    {"*x #a*", [][2]int{{3, 5}}, "Emphasis[Text(x ) Tag(#a)]"},
+		"InLinkText":    {"[#a](/u)", [][2]int{{1, 3}}, "Link[Tag(#a)]"},
+		"EscapedPrefix": {"\\*&amp; #a \\*", [][2]int{{8, 10}}, "Text(*& ) Tag(#a) Text( *)"},
+		"InCode":        {"`#a`", [][2]int{{1, 3}}, "Code(#a)"},
+		"IntoEmphasis":  {"#a*b*", [][2]int{{0, 3}}, "Text(#a) Emphasis[Text(b)]"},
+		"Overlapping":   {"#a #b", [][2]int{{0, 4}, {3, 5}}, "Tag(#a #) Text(b)"},
+		"SecondLine":    {"x\n y #a", [][2]int{{5, 7}}, "Text(x) SoftBreak Text(y ) Tag(#a)"},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			doc := Parse(tc.input)
+			Splice(doc, tc.input, tags(tc.input, tc.spans))
+			if got := dumpChildren(doc.FirstChild); got != tc.expected {
+				t.Fatalf("Splice(%q, %v) = %s, expected %s", tc.input, tc.spans, got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestSpliceShouldSetRanges(t *testing.T) {
+	input := "# H\n\n> x *y* #a &amp; b"
+	doc := Parse(input)
+	Splice(doc, input, tags(input, [][2]int{{13, 15}}))
+
+	got := []string{}
+	Walk(doc, func(n *Node, entering bool) bool {
+		if entering && n.Parent != nil && n.Parent.Type == PARAGRAPH {
+			got = append(got, span(input, n.Range))
+		}
+		return true
+	})
+	expected := []string{"x ", "*y*", " ", "#a", " &amp; b"}
+	if strings.Join(got, "|") != strings.Join(expected, "|") {
+		t.Fatalf("Splice(%q) ranges span %q, expected %q", input, got, expected)
+	}
+
+	tag := doc.LastChild.FirstChild.FirstChild.Next.Next.Next
+	if start := (Position{Line: 2, Column: 9, Offset: 13}); tag.Range.Start != start {
+		t.Fatalf("Splice(%q) tag starts at %+v, expected %+v", input, tag.Range.Start, start)
+	}
+}
+
+func TestSpliceShouldSkipNodesOutsideParagraphs(t *testing.T) {
+	input := "```\n#a\n```\n\n<div>#b</div>\n\n#c"
+	doc := Parse(input)
+	Splice(doc, input, tags(input, [][2]int{{4, 6}, {17, 19}, {27, 29}}))
+
+	if got := dump(doc); got != "Document[CodeBlock HTMLBlock Paragraph[Tag(#c)]]" {
+		t.Fatalf("Splice(%q) = %s", input, got)
+	}
+}
+
+// tags returns tag nodes for the spans of input.
+func tags(input string, spans [][2]int) []*Node {
+	nodes := []*Node{}
+	for _, s := range spans {
+		n := newNode(TAG)
+		n.Literal = input[s[0]:s[1]]
+		n.Range = Range{Start: Position{Offset: s[0]}, End: Position{Offset: s[1]}}
+		nodes = append(nodes, n)
+	}
+	return nodes
+}
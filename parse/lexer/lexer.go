@@ -2,14 +2,17 @@ package lexer
 
 import (
 	"bufio"
-	"bytes"
 	"io"
 	"log"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
 )
 
 type TokenType int
 
 const (
+	ERROR   TokenType = -2
 	ILLEGAL TokenType = -1
 	EOF     TokenType = 0
 
@@ -22,95 +25,214 @@ const (
 	WS       TokenType = 8
 	NL       TokenType = 9
 	TICK     TokenType = 10
+	TILDE    TokenType = 12
+	BANG     TokenType = 13
+)
+
+// Encoding is the unit columns and lengths are counted in. Editors speaking
+// LSP count UTF-16 code units.
+type Encoding string
+
+const (
+	BYTES Encoding = "Bytes"
+	RUNES Encoding = "Runes"
+	UTF16 Encoding = "UTF16"
 )
 
 type Token struct {
-	TokenType TokenType
-	Lit      string
-	LineNr   int
-	Length   int
-	Column   int
+	TokenType   TokenType
+	Lit         string
+	LineNr      int
+	Length      int
+	Column      int
+	RuneLength  int
+	RuneColumn  int
+	UTF16Length int
+	UTF16Column int
+	Offset      int // byte offset of the token in the input
+	EndOffset   int // byte offset just past the token
+}
+
+// ColumnIn returns the column of the token counted in e, BYTES by default.
+func (tk Token) ColumnIn(e Encoding) int {
+	switch e {
+	case RUNES:
+		return tk.RuneColumn
+	case UTF16:
+		return tk.UTF16Column
+	}
+	return tk.Column
+}
+
+// EndColumnIn returns the column just past the token counted in e.
+func (tk Token) EndColumnIn(e Encoding) int {
+	switch e {
+	case RUNES:
+		return tk.RuneColumn + tk.RuneLength
+	case UTF16:
+		return tk.UTF16Column + tk.UTF16Length
+	}
+	return tk.Column + tk.Length
+}
+
+// Width returns the length of s counted in e.
+func Width(s string, e Encoding) int {
+	switch e {
+	case RUNES:
+		return utf8.RuneCountInString(s)
+	case UTF16:
+		n := 0
+		for _, ch := range s {
+			n += utf16.RuneLen(ch)
+		}
+		return n
+	}
+	return len(s)
 }
 
 type Scanner struct {
-	r      *bufio.Reader
-	LineNr int
-	Column int
+	r           *bufio.Reader
+	LineNr      int
+	Column      int
+	RuneColumn  int
+	UTF16Column int
+	Offset      int
+	Logger      *log.Logger // nil discards log output
+	lit         []byte      // source bytes of the token being scanned
+	lastWidth   int
+	err         error
 }
 
-var eof = rune(0)
-var logger = log.Default()
+// eof is returned by read at the end of the input. It is no rune, so NUL
+// bytes are read like any other character.
+const eof = rune(-1)
 
 func NewScanner(r io.Reader) *Scanner {
-	return &Scanner{r: bufio.NewReader(r), LineNr: 0, Column: 1}
+	return &Scanner{r: bufio.NewReader(r), LineNr: 0, Column: 1, RuneColumn: 1, UTF16Column: 1, Logger: log.Default()}
 }
 
+// Err returns the first error other than io.EOF the reader failed with. Once
+// reading failed, Scan returns ERROR tokens.
+func (s *Scanner) Err() error {
+	return s.err
+}
+
+// Scan returns the next token. The Lit of a token holds its bytes as read,
+// including invalid UTF-8, which counts as one rune per byte.
 func (s *Scanner) Scan() Token {
-	token, lit := s.scanNext()
+	s.lit = s.lit[:0]
+	token := s.scanNext()
+	if token == EOF && s.err != nil {
+		token = ERROR
+	}
+	lit := string(s.lit)
 
 	result := Token{
-		TokenType: token,
-		Lit:      lit,
-		LineNr:   s.LineNr,
-		Length:   len(lit),
-		Column:   s.Column,
+		TokenType:   token,
+		Lit:         lit,
+		LineNr:      s.LineNr,
+		Length:      len(lit),
+		Column:      s.Column,
+		RuneLength:  utf8.RuneCountInString(lit),
+		RuneColumn:  s.RuneColumn,
+		UTF16Length: Width(lit, UTF16),
+		UTF16Column: s.UTF16Column,
+		Offset:      s.Offset,
+		EndOffset:   s.Offset + len(lit),
 	}
 
-	s.Column += len(lit)
+	s.Offset += len(lit)
+
+	if token != NL {
+		s.Column += result.Length
+		s.RuneColumn += result.RuneLength
+		s.UTF16Column += result.UTF16Length
+	}
 	return result
 }
 
-func (s *Scanner) scanNext() (TokenType, string) {
+func (s *Scanner) scanNext() TokenType {
 	ch := s.read()
 
 	if isText(ch) {
-		s.unread()
-		return s.scanText()
+		return s.scanWhile(TEXT, isText)
 	}
 
 	if isWhiteSpace(ch) {
-		s.unread()
-		return s.scanWhiteSpace()
+		return s.scanWhile(WS, isWhiteSpace)
 	}
 
 	if isNewLine(ch) {
-		s.unread()
-		return s.scanNewLine()
+		return s.scanNewLine(ch)
 	}
 
 	switch ch {
 	case '#':
-		s.unread()
-		return s.scanHash()
+		return s.scanRun(HASH, ch)
 	case eof:
-		return EOF, string(ch)
+		return EOF
 	case '`':
-		return TICK, string(ch)
+		return s.scanRun(TICK, ch)
+	case '~':
+		return s.scanRun(TILDE, ch)
 	case '[':
-		return LEFTBRK, string(ch)
+		return LEFTBRK
 	case ']':
-		return RIGHTBRK, string(ch)
+		return RIGHTBRK
 	case '(':
-		return LEFTPRN, string(ch)
+		return LEFTPRN
 	case ')':
-		return RIGHTPRN, string(ch)
+		return RIGHTPRN
+	case '!':
+		return BANG
 	}
 
-	return ILLEGAL, string(ch)
+	return ILLEGAL
 }
 
+// read returns the next rune and appends its bytes to the token. An invalid
+// byte is returned as utf8.RuneError, but the byte itself goes to the token.
 func (s *Scanner) read() rune {
-	ch, _, err := s.r.ReadRune()
+	s.lastWidth = 0
+	if s.err != nil {
+		return eof
+	}
+
+	first, _ := s.r.Peek(1)
+	ch, size, err := s.r.ReadRune()
 	if err != nil {
+		if err != io.EOF {
+			s.err = err
+			s.logf("ERROR: read failed: %v", err)
+		}
 		return eof
 	}
+
+	s.lastWidth = size
+	if ch == utf8.RuneError && size == 1 {
+		s.lit = append(s.lit, first[0])
+	} else {
+		s.lit = utf8.AppendRune(s.lit, ch)
+	}
 	return ch
 }
 
 func (s *Scanner) unread() {
-	err := s.r.UnreadRune()
-	if err != nil {
-		logger.Print("ERROR: unread rune", err)
+	if s.lastWidth == 0 {
+		return
+	}
+
+	if err := s.r.UnreadRune(); err != nil {
+		s.logf("ERROR: unread rune: %v", err)
+		return
+	}
+	s.lit = s.lit[:len(s.lit)-s.lastWidth]
+	s.lastWidth = 0
+}
+
+func (s *Scanner) logf(format string, args ...interface{}) {
+	if s.Logger != nil {
+		s.Logger.Printf(format, args...)
 	}
 }
 
@@ -119,93 +241,60 @@ func isNewLine(ch rune) bool {
 }
 
 func isPunc(ch rune) bool {
-	return ch == '.' || ch == ',' || ch == '-' || ch == '_'
+	if ch < utf8.RuneSelf {
+		return ch == '.' || ch == ',' || ch == '-' || ch == '_'
+	}
+	return unicode.IsPunct(ch) || unicode.IsSymbol(ch)
 }
 
 func isNum(ch rune) bool {
-	return ch >= '0' && ch <= '9'
+	return unicode.IsNumber(ch)
 }
 
 func isAlpha(ch rune) bool {
-	return (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z')
+	return unicode.IsLetter(ch) || unicode.IsMark(ch)
+}
+
+// Zero width (non-)joiners glue emoji sequences and some scripts together.
+func isJoiner(ch rune) bool {
+	return ch == '\u200c' || ch == '\u200d'
 }
 
 func isText(ch rune) bool {
-	return isAlpha(ch) || isNum(ch) || isPunc(ch)
+	return isAlpha(ch) || isNum(ch) || isPunc(ch) || isJoiner(ch)
 }
 
 func isWhiteSpace(ch rune) bool {
 	return ch == ' ' || ch == '\t'
 }
 
-func (s *Scanner) scanNewLine() (TokenType, string) {
-	nl := s.read()
-
+func (s *Scanner) scanNewLine(nl rune) TokenType {
 	if nl == '\r' {
-		win := s.read()
-		if win == '\n' {
-			return NL, string(nl) + string(win)
-		} else {
+		if win := s.read(); win != '\n' {
 			s.unread()
-			return NL, string(nl)
 		}
 	}
 
 	s.LineNr++
 	s.Column = 1
-	return NL, string(nl)
-}
-
-func (s *Scanner) scanHash() (TokenType, string) {
-	var buf bytes.Buffer
-	buf.WriteRune(s.read())
-
-	for {
-		if ch := s.read(); ch == eof {
-			break
-		} else if ch != '#' {
-			s.unread()
-			break
-		} else {
-			_, _ = buf.WriteRune(ch)
-		}
-	}
-
-	return HASH, buf.String()
+	s.RuneColumn = 1
+	s.UTF16Column = 1
+	return NL
 }
 
-func (s *Scanner) scanWhiteSpace() (TokenType, string) {
-	var buf bytes.Buffer
-	buf.WriteRune(s.read())
-
-	for {
-		if ch := s.read(); ch == eof {
-			break
-		} else if !isWhiteSpace(ch) {
-			s.unread()
-			break
-		} else {
-			_, _ = buf.WriteRune(ch)
-		}
-	}
-
-	return WS, buf.String()
+// scanRun reads the repetitions of the first rune of a run.
+func (s *Scanner) scanRun(token TokenType, first rune) TokenType {
+	return s.scanWhile(token, func(ch rune) bool { return ch == first })
 }
 
-func (s *Scanner) scanText() (TokenType, string) {
-	var buf bytes.Buffer
-	buf.WriteRune(s.read())
-
+func (s *Scanner) scanWhile(token TokenType, accept func(rune) bool) TokenType {
 	for {
 		if ch := s.read(); ch == eof {
 			break
-		} else if !isText(ch) {
+		} else if !accept(ch) {
 			s.unread()
 			break
-		} else {
-			_, _ = buf.WriteRune(ch)
 		}
 	}
-
-	return TEXT, buf.String()
+	return token
 }
@@ -1,8 +1,14 @@
 package lexer
 
 import (
+	"bytes"
+	"errors"
+	"io"
+	"log"
+	"reflect"
 	"strings"
 	"testing"
+	"testing/iotest"
 )
 
 func TestScanShouldReturnToken(t *testing.T) {
@@ -17,10 +23,17 @@ func TestScanShouldReturnToken(t *testing.T) {
 		"LeftParen":      {"(", LEFTPRN},
 		"RightParen":     {")", RIGHTPRN},
 		"Tick":           {"`", TICK},
+		"Tilde":          {"~", TILDE},
+		"Bang":           {"!", BANG},
 		"Text":           {"abc", TEXT},
 		"TextSlug":       {"-b-c", TEXT},
 		"TextUnderscore": {"_b_c", TEXT},
 		"TextNumbers":    {"123ab", TEXT},
+		"TextAccented":   {"éa", TEXT},
+		"TextCJK":        {"日本語", TEXT},
+		"TextCyrillic":   {"привет", TEXT},
+		"TextEmoji":      {"🎉", TEXT},
+		"TextCombining":  {"e\u0301", TEXT},
 		"WhiteSpace":     {" ", WS},
 		"WhiteSpaceTab":  {string('\t'), WS},
 		"NewLineLinux":   {string('\n'), NL},
@@ -46,10 +59,17 @@ func TestScanShouldReturnLiteral(t *testing.T) {
 	}{
 		"Hash":           {"#", "#"},
 		"HashContiguous": {"###", "###"},
+		"TickContiguous": {"```go", "```"},
+		"TildeContigous": {"~~~~ x", "~~~~"},
+		"HashThenTick":   {"##`", "##"},
 		"Text":           {"abc", "abc"},
 		"TextSlug":       {"a-b-c", "a-b-c"},
 		"TextUnderscore": {"a_b_c", "a_b_c"},
 		"TextNumbers":    {"925abc", "925abc"},
+		"TextAccented":   {"café au lait", "café"},
+		"TextMixed":      {"naïve日本語[x]", "naïve日本語"},
+		"TextEmojiZWJ":   {"👩\u200d💻 coder", "👩\u200d💻"},
+		"TextArabic":     {"مرحبا بالعالم", "مرحبا"},
 		"WSContiguous":   {"   ", "   "},
 		"WSWithTab":      {"  	", "  	"},
 		"NLLinuxSingle":  {string('\n') + string('\n'), string('\n')},
@@ -79,6 +99,8 @@ func TestScanTextLiteralShouldNotOverrun(t *testing.T) {
 		"RightPrn":      {"ast)", "ast"},
 		"Hash":          {"ast#", "ast"},
 		"TICK":          {"ast`", "ast"},
+		"TILDE":         {"ast~", "ast"},
+		"Bang":          {"ast!", "ast"},
 		"WhiteSpace":    {"ast ", "ast"},
 		"WhiteSpaceTab": {"ast	", "ast"},
 		"NewlineNix":    {"ast" + string('\n'), "ast"},
@@ -138,6 +160,9 @@ func TestScanShouldReturnLength(t *testing.T) {
 		"TextSlug":       {"a-b-c", 5},
 		"TextUnderscore": {"a_b_c", 5},
 		"TextNumbers":    {"925abc", 6},
+		"TextAccented":   {"café", 5},
+		"TextCJK":        {"日本語", 9},
+		"TextEmoji":      {"🎉", 4},
 		"WSContiguous":   {"   ", 3},
 		"WSWithTab":      {"  	", 3},
 		"NLLinuxSingle":  {string('\n') + string('\n'), 1},
@@ -156,6 +181,41 @@ func TestScanShouldReturnLength(t *testing.T) {
 	}
 }
 
+func TestScanShouldReturnRuneLength(t *testing.T) {
+	tests := map[string]struct {
+		input string
+		want  int
+	}{
+		"Text":         {"abc", 3},
+		"TextAccented": {"café", 4},
+		"TextCJK":      {"日本語", 3},
+		"TextEmoji":    {"🎉", 1},
+		"TextEmojiZWJ": {"👩\u200d💻", 3},
+		"Hash":         {"###", 3},
+		"Invalid":      {"\xffab", 3},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			lex := NewScanner(strings.NewReader(tc.input))
+			got := lex.Scan()
+			if got.RuneLength != tc.want {
+				t.Fatalf(`Scan failed "%s" expected rune length of %v got %v`, tc.input, tc.want, got.RuneLength)
+			}
+		})
+	}
+}
+
+func TestScanLengthShouldCountSourceBytes(t *testing.T) {
+	input := "\xffab"
+	want := 3
+	lex := NewScanner(strings.NewReader(input))
+	got := lex.Scan()
+	if got.Length != want {
+		t.Fatalf(`Scan failed "%s" expected length of %v got %v`, input, want, got.Length)
+	}
+}
+
 func TestScanReturnsTokenLineNr(t *testing.T) {
 	input := `asd
 ast
@@ -181,6 +241,19 @@ func TestScanReturnsTokenColumn(t *testing.T) {
 	}
 }
 
+func TestScanReturnsTokenColumnsForUnicode(t *testing.T) {
+	input := `日本 café x`
+	lex := NewScanner(strings.NewReader(input))
+	_ = lex.Scan()
+	_ = lex.Scan()
+	_ = lex.Scan()
+	_ = lex.Scan()
+	got := lex.Scan()
+	if got.Lit != "x" || got.Column != 14 || got.RuneColumn != 9 {
+		t.Fatalf(`Scan failed "%s" expected x at column 14 and rune column 9 got %q at %v and %v`, input, got.Lit, got.Column, got.RuneColumn)
+	}
+}
+
 func TestScanReturnsTokenColumnResetAfterNewline(t *testing.T) {
 	input := `asd
 ast asg`
@@ -192,3 +265,150 @@ ast asg`
 		t.Fatalf(`Scan failed "%s" expected column nr %v got %v`, input, want, got.Column)
 	}
 }
+
+func TestScanReturnsFirstColumnOnLineAfterNewline(t *testing.T) {
+	input := `asd
+ast asg`
+	want := 1
+	lex := NewScanner(strings.NewReader(input))
+	_ = lex.Scan()
+	_ = lex.Scan()
+	got := lex.Scan()
+	if got.Column != want || got.RuneColumn != want {
+		t.Fatalf(`Scan failed "%s" expected column nr %v got %v and rune column %v`, input, want, got.Column, got.RuneColumn)
+	}
+}
+
+func TestScanShouldReturnErrorTokenOnReadFailure(t *testing.T) {
+	failure := errors.New("connection reset")
+	lex := NewScanner(io.MultiReader(strings.NewReader("abc "), iotest.ErrReader(failure)))
+
+	for _, want := range []TokenType{TEXT, WS, ERROR, ERROR} {
+		if tk := lex.Scan(); tk.TokenType != want {
+			t.Fatalf("Scan() = %v, expected %v", tk.TokenType, want)
+		}
+	}
+	if err := lex.Err(); !errors.Is(err, failure) {
+		t.Fatalf("Err() = %v, expected %v", err, failure)
+	}
+}
+
+func TestScanShouldNotReportEOFAsError(t *testing.T) {
+	lex := NewScanner(strings.NewReader("abc"))
+
+	lex.Scan()
+	if tk := lex.Scan(); tk.TokenType != EOF {
+		t.Fatalf("Scan() = %v, expected %v", tk.TokenType, EOF)
+	}
+	if err := lex.Err(); err != nil {
+		t.Fatalf("Err() = %v, expected nil", err)
+	}
+}
+
+func TestScanShouldLogToInjectedLogger(t *testing.T) {
+	failure := errors.New("connection reset")
+	scanAll := func(logger *log.Logger) {
+		lex := NewScanner(io.MultiReader(strings.NewReader("abc"), iotest.ErrReader(failure)))
+		lex.Logger = logger
+		for tk := lex.Scan(); tk.TokenType != ERROR && tk.TokenType != EOF; tk = lex.Scan() {
+		}
+	}
+
+	var buf bytes.Buffer
+	scanAll(log.New(&buf, "", 0))
+	if !strings.Contains(buf.String(), "connection reset") {
+		t.Fatalf("Logger got %q, expected the read failure", buf.String())
+	}
+
+	var std bytes.Buffer
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(&std)
+	scanAll(nil)
+	if std.Len() > 0 {
+		t.Fatalf("nil Logger logged %q to the standard logger, expected nothing", std.String())
+	}
+}
+
+func TestScanOffsetsShouldSliceInput(t *testing.T) {
+	input := "# 日本\r\n[[café]]\n`x` (y)\r"
+	lex := NewScanner(strings.NewReader(input))
+
+	offset := 0
+	for tk := lex.Scan(); tk.TokenType != EOF; tk = lex.Scan() {
+		if tk.Offset != offset || input[tk.Offset:tk.EndOffset] != tk.Lit {
+			t.Fatalf(`Scan failed "%s" expected %q at offset %v got offsets %v to %v`, input, tk.Lit, offset, tk.Offset, tk.EndOffset)
+		}
+		offset = tk.EndOffset
+	}
+	if offset != len(input) {
+		t.Fatalf(`Scan failed "%s" expected to end at offset %v got %v`, input, len(input), offset)
+	}
+}
+
+func TestScanShouldKeepNULAndInvalidBytes(t *testing.T) {
+	input := "a\x00b \xff\xfe c\x00"
+	lex := NewScanner(strings.NewReader(input))
+
+	lits := []string{}
+	for tk := lex.Scan(); tk.TokenType != EOF; tk = lex.Scan() {
+		if input[tk.Offset:tk.EndOffset] != tk.Lit || tk.Length != len(tk.Lit) {
+			t.Fatalf(`Scan failed %q expected %q at offsets %v to %v`, input, tk.Lit, tk.Offset, tk.EndOffset)
+		}
+		lits = append(lits, tk.Lit)
+	}
+
+	expected := []string{"a", "\x00", "b", " ", "\xff\xfe", " ", "c", "\x00"}
+	if !reflect.DeepEqual(lits, expected) {
+		t.Fatalf(`Scan failed %q expected %q got %q`, input, expected, lits)
+	}
+}
+
+func TestScanReturnsTokenColumnsInEncodings(t *testing.T) {
+	input := "é🎉 x"
+	lex := NewScanner(strings.NewReader(input))
+	_ = lex.Scan()
+	_ = lex.Scan()
+	got := lex.Scan()
+
+	want := map[Encoding]int{BYTES: 8, RUNES: 4, UTF16: 5}
+	for e, column := range want {
+		if got.ColumnIn(e) != column || got.EndColumnIn(e) != column+1 {
+			t.Fatalf(`Scan failed "%s" expected x at %v column %v got %v to %v`, input, e, column, got.ColumnIn(e), got.EndColumnIn(e))
+		}
+	}
+}
+
+func TestWidthShouldCountEncodingUnits(t *testing.T) {
+	tests := map[Encoding]int{BYTES: 10, RUNES: 4, UTF16: 5}
+	for e, want := range tests {
+		if got := Width("a日é🎉", e); got != want {
+			t.Fatalf(`Width("a日é🎉", %v) = %v expected %v`, e, got, want)
+		}
+	}
+}
+
+func TestScanCountsLinesForAllNewLineStyles(t *testing.T) {
+	tests := map[string]struct {
+		input string
+		line  int
+	}{
+		"Linux":        {"a\nb", 1},
+		"Mac":          {"a\rb", 1},
+		"Dos":          {"a\r\nb", 1},
+		"MacThenDos":   {"a\r\r\nb", 2},
+		"DosThenLinux": {"a\r\n\nb", 2},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			lex := NewScanner(strings.NewReader(tc.input))
+			tk := lex.Scan()
+			for tk.Lit != "b" {
+				tk = lex.Scan()
+			}
+			if tk.LineNr != tc.line || tk.Column != 1 || tk.RuneColumn != 1 || tk.UTF16Column != 1 {
+				t.Fatalf(`Scan failed %q expected b at line %v column 1 got line %v column %v`, tc.input, tc.line, tk.LineNr, tk.Column)
+			}
+		})
+	}
+}
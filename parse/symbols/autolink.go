@@ -0,0 +1,207 @@
+package symbols
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/siasmey/markdown/parse/lexer"
+)
+
+var emailPattern = regexp.MustCompile("^[a-zA-Z0-9.!#$%&'*+/=?^_`{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$")
+var entityPattern = regexp.MustCompile(`&[a-zA-Z0-9]+;$`)
+
+// parseAutolink reads <scheme:...> and <user@host> autolinks.
+func (p *Parser) parseAutolink(open lexer.Token) (Symbol, bool) {
+	tokens := []lexer.Token{open}
+	content := []lexer.Token{}
+
+	for {
+		tk := p.next()
+		tokens = append(tokens, tk)
+		if tk.TokenType == lexer.ILLEGAL && tk.Lit == ">" {
+			break
+		}
+		if tk.TokenType == lexer.WS || tk.TokenType == lexer.NL || tk.TokenType == lexer.EOF || tk.Lit == "<" {
+			p.backup(false, tokens[1:]...)
+			return Symbol{}, false
+		}
+		content = append(content, tk)
+	}
+
+	text := literal(content)
+	value := text
+	switch {
+	case isURI(text):
+	case emailPattern.MatchString(text):
+		value = "mailto:" + text
+	default:
+		p.backup(false, tokens[1:]...)
+		return Symbol{}, false
+	}
+
+	closing := tokens[len(tokens)-1]
+	return p.urlSymbol(AUTOLINK, literal(tokens), value, content, open, closing), true
+}
+
+func isURI(text string) bool {
+	i := strings.IndexByte(text, ':')
+	if i < 2 || i > 32 || !isASCIILetter(text[0]) {
+		return false
+	}
+
+	for j := 1; j < i; j++ {
+		if ch := text[j]; !isASCIILetter(ch) && !isASCIIDigit(ch) && ch != '+' && ch != '.' && ch != '-' {
+			return false
+		}
+	}
+	return true
+}
+
+func isASCIILetter(ch byte) bool {
+	return ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z'
+}
+
+func isASCIIDigit(ch byte) bool {
+	return ch >= '0' && ch <= '9'
+}
+
+// parseBareURL follows the GFM extended autolink rules for http://, https://
+// and www. links written straight into the text.
+func (p *Parser) parseBareURL(start lexer.Token) (Symbol, bool) {
+	lower := strings.ToLower(start.Lit)
+	if lower != "http" && lower != "https" && !strings.HasPrefix(lower, "www.") {
+		return Symbol{}, false
+	}
+	if prev := p.prevOther; p.followsText(start) && prev.Lit != "*" {
+		return Symbol{}, false
+	}
+
+	tokens := []lexer.Token{start}
+	for {
+		tk := p.next()
+		if tk.TokenType == lexer.WS || tk.TokenType == lexer.NL || tk.TokenType == lexer.EOF || tk.Lit == "<" {
+			p.backup(false, tk)
+			break
+		}
+		tokens = append(tokens, tk)
+	}
+
+	n := bareURLLength(literal(tokens))
+	if n == 0 {
+		p.backup(false, tokens[1:]...)
+		return Symbol{}, false
+	}
+
+	url, rest := cutTokens(tokens, n)
+	p.backup(false, rest...)
+
+	text := literal(url)
+	value := text
+	if strings.HasPrefix(strings.ToLower(text), "www.") {
+		value = "http://" + text
+	}
+	return p.urlSymbol(BARE, text, value, url, url[0], url[len(url)-1]), true
+}
+
+func bareURLLength(text string) int {
+	prefix := ""
+	for _, scheme := range []string{"http://", "https://", "www."} {
+		if len(text) >= len(scheme) && strings.EqualFold(text[:len(scheme)], scheme) {
+			prefix = scheme
+			break
+		}
+	}
+	if prefix == "" {
+		return 0
+	}
+
+	text = trimURLPunctuation(text)
+	if len(text) <= len(prefix) {
+		return 0
+	}
+	rest := text[len(prefix):]
+	if prefix == "www." {
+		rest = text
+	}
+
+	domain := rest
+	if i := strings.IndexFunc(rest, isNotDomainRune); i >= 0 {
+		domain = rest[:i]
+	}
+	if !isValidDomain(domain) {
+		return 0
+	}
+	return len(text)
+}
+
+func trimURLPunctuation(text string) string {
+	for text != "" {
+		last := text[len(text)-1]
+		switch {
+		case strings.IndexByte("?!.,:*_~", last) >= 0:
+			text = text[:len(text)-1]
+		case last == ')' && strings.Count(text, ")") > strings.Count(text, "("):
+			text = text[:len(text)-1]
+		case last == ';' && entityPattern.MatchString(text):
+			text = text[:entityPattern.FindStringIndex(text)[0]]
+		default:
+			return text
+		}
+	}
+	return text
+}
+
+// A valid domain has at least one period and no underscores in its last two
+// segments.
+func isValidDomain(domain string) bool {
+	segments := strings.Split(domain, ".")
+	if len(segments) < 2 {
+		return false
+	}
+
+	for i, segment := range segments {
+		if segment == "" || i >= len(segments)-2 && strings.Contains(segment, "_") {
+			return false
+		}
+	}
+	return true
+}
+
+func isNotDomainRune(ch rune) bool {
+	return !isAlphaRune(ch) && !unicode.IsNumber(ch) && ch != '_' && ch != '-' && ch != '.'
+}
+
+func cutTokens(tokens []lexer.Token, n int) ([]lexer.Token, []lexer.Token) {
+	size := 0
+	for i, tk := range tokens {
+		switch {
+		case size+len(tk.Lit) <= n:
+			size += len(tk.Lit)
+		case size < n:
+			head, rest := splitToken(tk, n-size)
+			return append(tokens[:i:i], head), append([]lexer.Token{rest}, tokens[i+1:]...)
+		default:
+			return tokens[:i], tokens[i:]
+		}
+	}
+	return tokens, nil
+}
+
+func (p *Parser) urlSymbol(kind LinkKind, lit string, value string, content []lexer.Token, first lexer.Token, last lexer.Token) Symbol {
+	text := p.newSpan(content)
+	return Symbol{
+		Type:        LINK,
+		Kind:        kind,
+		Lit:         lit,
+		Value:       value,
+		LineNo:      first.LineNr,
+		EndLineNo:   last.LineNr,
+		CharStart:   p.column(first),
+		CharEnd:     p.endColumn(last),
+		Offset:      first.Offset,
+		EndOffset:   last.EndOffset,
+		Text:        text,
+		Destination: &Span{Value: value, LineNo: text.LineNo, CharStart: text.CharStart, CharEnd: text.CharEnd, Offset: text.Offset, EndOffset: text.EndOffset, Range: text.Range},
+	}
+}
@@ -0,0 +1,150 @@
+package symbols
+
+import "testing"
+
+func TestParseShouldReturnAutolink(t *testing.T) {
+	tests := map[string]struct {
+		input string
+		lit   string
+		value string
+		text  string
+	}{
+		"HTTPS":     {"<https://example.com>", "<https://example.com>", "https://example.com", "https://example.com"},
+		"Query":     {"go <http://foo.bar.baz/test?q=hello&id=22&boolean> now", "<http://foo.bar.baz/test?q=hello&id=22&boolean>", "http://foo.bar.baz/test?q=hello&id=22&boolean", "http://foo.bar.baz/test?q=hello&id=22&boolean"},
+		"Scheme":    {"<irc://foo.bar:2233/baz>", "<irc://foo.bar:2233/baz>", "irc://foo.bar:2233/baz", "irc://foo.bar:2233/baz"},
+		"Email":     {"<me@example.com>", "<me@example.com>", "mailto:me@example.com", "me@example.com"},
+		"EmailPlus": {"<foo+special@Bar.baz-bar0.com>", "<foo+special@Bar.baz-bar0.com>", "mailto:foo+special@Bar.baz-bar0.com", "foo+special@Bar.baz-bar0.com"},
+		"Fragment":  {"<https://x.org/#top>", "<https://x.org/#top>", "https://x.org/#top", "https://x.org/#top"},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			res, err := Parse(tc.input)
+			if len(res.Links) != 1 {
+				failMessageInt(t, tc.input, len(res.Links), err, 1)
+			}
+
+			link := res.Links[0]
+			if link.Kind != AUTOLINK {
+				failMessageString(t, tc.input, string(link.Kind), err, string(AUTOLINK))
+			}
+			if link.Lit != tc.lit {
+				failMessageString(t, tc.input, link.Lit, err, tc.lit)
+			}
+			if link.Value != tc.value || spanValue(link.Destination) != tc.value {
+				failMessageString(t, tc.input, link.Value, err, tc.value)
+			}
+			if spanValue(link.Text) != tc.text {
+				failMessageString(t, tc.input, spanValue(link.Text), err, tc.text)
+			}
+			if len(res.Tags) != 0 {
+				failMessageInt(t, tc.input, len(res.Tags), err, 0)
+			}
+		})
+	}
+}
+
+func TestParseShouldNotReturnAutolink(t *testing.T) {
+	tests := map[string]string{
+		"Space":       "<https://foo.bar/baz bim>",
+		"NoScheme":    "<foo.bar.baz>",
+		"ShortScheme": "<m:abc>",
+		"Unclosed":    "<https://example.com",
+		"NotEmail":    "<foo\\+@bar.example.com>",
+		"HTMLTag":     "<div>",
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			res, err := Parse(input)
+			for _, link := range res.Links {
+				if link.Kind == AUTOLINK {
+					failMessageString(t, input, link.Lit, err, "")
+				}
+			}
+		})
+	}
+}
+
+func TestParseShouldReturnBareURL(t *testing.T) {
+	tests := map[string]struct {
+		input string
+		lit   string
+		value string
+	}{
+		"HTTPS":          {"see https://example.com here", "https://example.com", "https://example.com"},
+		"WWW":            {"www.commonmark.org", "www.commonmark.org", "http://www.commonmark.org"},
+		"TrailingPeriod": {"Visit www.commonmark.org/help for more.", "www.commonmark.org/help", "http://www.commonmark.org/help"},
+		"TrailingDots":   {"Visit www.commonmark.org/a.b.", "www.commonmark.org/a.b", "http://www.commonmark.org/a.b"},
+		"Parens":         {"www.google.com/search?q=Markup+(business)", "www.google.com/search?q=Markup+(business)", "http://www.google.com/search?q=Markup+(business)"},
+		"UnbalancedParn": {"(www.google.com/search?q=Markup+(business))", "www.google.com/search?q=Markup+(business)", "http://www.google.com/search?q=Markup+(business)"},
+		"ExtraParens":    {"www.google.com/search?q=Markup+(business)))", "www.google.com/search?q=Markup+(business)", "http://www.google.com/search?q=Markup+(business)"},
+		"Entity":         {"www.google.com/search?q=commonmark&hl;", "www.google.com/search?q=commonmark", "http://www.google.com/search?q=commonmark"},
+		"NotEntity":      {"www.google.com/search?q=commonmark&hl=en", "www.google.com/search?q=commonmark&hl=en", "http://www.google.com/search?q=commonmark&hl=en"},
+		"LessThan":       {"www.commonmark.org/he<lp", "www.commonmark.org/he", "http://www.commonmark.org/he"},
+		"Port":           {"http://example.com:8080/x?", "http://example.com:8080/x", "http://example.com:8080/x"},
+		"Fragment":       {"https://example.com/page#section!", "https://example.com/page#section", "https://example.com/page#section"},
+		"Emphasis":       {"*https://example.com*", "https://example.com", "https://example.com"},
+		"Path":           {"https://en.wikipedia.org/wiki/Go_(language)", "https://en.wikipedia.org/wiki/Go_(language)", "https://en.wikipedia.org/wiki/Go_(language)"},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			res, err := Parse(tc.input)
+			if len(res.Links) != 1 {
+				failMessageInt(t, tc.input, len(res.Links), err, 1)
+			}
+
+			link := res.Links[0]
+			if link.Kind != BARE {
+				failMessageString(t, tc.input, string(link.Kind), err, string(BARE))
+			}
+			if link.Lit != tc.lit || spanValue(link.Text) != tc.lit {
+				failMessageString(t, tc.input, link.Lit, err, tc.lit)
+			}
+			if link.Value != tc.value {
+				failMessageString(t, tc.input, link.Value, err, tc.value)
+			}
+			if len(res.Tags) != 0 {
+				failMessageInt(t, tc.input, len(res.Tags), err, 0)
+			}
+		})
+	}
+}
+
+func TestParseShouldReturnBareURLPosition(t *testing.T) {
+	input := "x\nsee https://example.com/a."
+
+	res, err := Parse(input)
+	link := res.Links[0]
+	if link.LineNo != 1 || link.EndLineNo != 1 || link.CharStart != 5 || link.CharEnd != 26 {
+		failMessageInt(t, input, link.CharEnd, err, 26)
+	}
+	if link.Text.CharStart != 5 || link.Text.CharEnd != 26 {
+		failMessageInt(t, input, link.Text.CharEnd, err, 26)
+	}
+}
+
+func TestParseShouldNotReturnBareURL(t *testing.T) {
+	tests := map[string]string{
+		"NoPeriod":        "http://localhost/x",
+		"UnderscoreLast":  "www.example_site.com",
+		"GluedToWord":     "xhttps://example.com",
+		"SchemeOnly":      "https://",
+		"WWWOnly":         "www.",
+		"InsideLink":      "[https://example.com](u)",
+		"InsideCodeSpan":  "`https://example.com`",
+		"InsideCodeBlock": "```\nhttps://example.com\n```",
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			res, err := Parse(input)
+			for _, link := range res.Links {
+				if link.Kind == BARE {
+					failMessageString(t, input, link.Lit, err, "")
+				}
+			}
+		})
+	}
+}
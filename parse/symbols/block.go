@@ -0,0 +1,367 @@
+package symbols
+
+import (
+	"strings"
+
+	"github.com/siasmey/markdown/parse/lexer"
+)
+
+const tabStop = 4
+
+func (p *Parser) parseLineStart() (Symbol, bool) {
+	tk := p.next()
+	line := []lexer.Token{tk}
+	indent := 0
+
+	if tk.TokenType == lexer.WS {
+		indent = indentWidth(tk.Lit)
+		tk = p.next()
+		line = append(line, tk)
+	}
+
+	base := 0
+	if p.listIndent >= 0 {
+		base = p.listIndent
+	}
+
+	switch {
+	case tk.TokenType == lexer.NL || tk.TokenType == lexer.EOF:
+		p.paragraph = false
+	case tk == p.underline:
+		p.paragraph = false
+	case indent-base >= tabStop && !p.paragraph:
+		return p.parseIndentedCode(line, base+tabStop), true
+	case isFence(tk) && indent-base < tabStop:
+		if sym, ok := p.parseFencedCode(line, indent); ok {
+			return sym, true
+		}
+		p.paragraph = true
+		return Symbol{}, false
+	default:
+		p.lineHead = p.contentHead(tk)
+		if tk.TokenType == lexer.LEFTBRK && !p.paragraph {
+			if sym, ok := p.parseDefinition(line); ok {
+				return sym, true
+			}
+		}
+		contentIndent, marker := p.parseListMarker(indent, tk)
+		if marker {
+			p.listIndent = contentIndent
+		} else if indent < p.listIndent && !p.paragraph {
+			p.listIndent = -1
+		}
+		if !p.paragraph && !marker {
+			p.paragraph = true
+			p.backup(false, line...)
+			return p.parseSetextHeading(base)
+		}
+		p.paragraph = true
+	}
+
+	p.backup(false, line...)
+	return Symbol{}, false
+}
+
+// contentHead returns the token an ATX heading would start with on a line
+// beginning with tk, the first one behind its block quote and list markers.
+func (p *Parser) contentHead(tk lexer.Token) lexer.Token {
+	read := []lexer.Token{}
+	next := func() lexer.Token {
+		tk := p.next()
+		read = append(read, tk)
+		return tk
+	}
+
+	head := tk
+	for head.TokenType != lexer.EOF {
+		after := next()
+		quote := head.TokenType == lexer.ILLEGAL && head.Lit == ">"
+		marker := quote || head.TokenType == lexer.ILLEGAL && (head.Lit == "*" || head.Lit == "+") ||
+			head.TokenType == lexer.TEXT && (head.Lit == "-" || isOrderedMarker(head.Lit))
+		if head.TokenType == lexer.TEXT && isDigits(head.Lit) && after.TokenType == lexer.RIGHTPRN {
+			marker, after = true, next()
+		}
+
+		if marker && after.TokenType == lexer.WS {
+			head = next()
+		} else if quote {
+			head = after
+		} else {
+			break
+		}
+	}
+	p.backup(false, read...)
+	return head
+}
+
+// parseSetextHeading looks ahead from the first line of a paragraph for the
+// underline of a setext heading. Every line read is handed back; when one of
+// them underlines the lines before it, the heading is returned and its text
+// is parsed for symbols like the text of an ATX heading.
+func (p *Parser) parseSetextHeading(base int) (Symbol, bool) {
+	lines := [][]lexer.Token{}
+	level := 0
+	for more := true; more && level == 0; {
+		var line []lexer.Token
+		if line, more = p.readLine(); len(line) == 0 {
+			break
+		}
+		if len(lines) == 0 {
+			more = more && !startsBlock(line, base)
+		} else if level = setextLevel(line, base); level == 0 && (isBlank(line) || startsBlock(line, base)) {
+			more = false
+		}
+		lines = append(lines, line)
+	}
+
+	read := []lexer.Token{}
+	for _, line := range lines {
+		read = append(read, line...)
+	}
+	p.backup(false, read...)
+	if level == 0 {
+		return Symbol{}, false
+	}
+
+	text := lines[:len(lines)-1]
+	values := []string{}
+	for _, line := range text {
+		values = append(values, strings.Trim(trimNewLine(literal(line)), " \t"))
+	}
+	last := text[len(text)-1]
+	p.headingEnd = last[len(last)-1]
+
+	underline := trimSpace(lines[len(lines)-1])
+	p.underline = underline[0]
+	first := trimSpace(text[0])[0]
+	end := underline[len(underline)-1]
+	return Symbol{
+		Type:      headings[level-1],
+		Lit:       literal(read[indexOf(read, first) : indexOf(read, end)+1]),
+		Value:     strings.Join(values, "\n"),
+		LineNo:    first.LineNr,
+		EndLineNo: end.LineNr,
+		Level:     level,
+		CharStart: p.column(first),
+		CharEnd:   p.endColumn(end),
+		Offset:    first.Offset,
+		EndOffset: end.EndOffset,
+	}, true
+}
+
+// setextLevel returns 1 or 2 for a line of = or - underlining a setext heading
+// and 0 for any other line.
+func setextLevel(line []lexer.Token, base int) int {
+	indent := 0
+	if line[0].TokenType == lexer.WS {
+		indent = indentWidth(line[0].Lit)
+	}
+	tokens := trimSpace(line)
+	if indent < base || indent-base >= tabStop || len(tokens) == 0 {
+		return 0
+	}
+
+	if len(tokens) == 1 && tokens[0].TokenType == lexer.TEXT && strings.Trim(tokens[0].Lit, "-") == "" {
+		return 2
+	}
+	for _, tk := range tokens {
+		if tk.TokenType != lexer.ILLEGAL || tk.Lit != "=" {
+			return 0
+		}
+	}
+	return 1
+}
+
+// startsBlock reports whether a line starts a block that ends a paragraph
+// before it, or cannot be the first line of one itself.
+func startsBlock(line []lexer.Token, base int) bool {
+	i, indent := 0, 0
+	if line[0].TokenType == lexer.WS {
+		indent = indentWidth(line[0].Lit)
+		i++
+	}
+	if indent-base >= tabStop || i == len(line) {
+		return false
+	}
+
+	tk, after := line[i], lexer.Token{}
+	if i+1 < len(line) {
+		after = line[i+1]
+	}
+	return isFence(tk) || isHeadingStart(tk, after) || isListItemStart(tk, after) ||
+		tk.TokenType == lexer.ILLEGAL && tk.Lit == ">" || isThematicBreak(line[i:])
+}
+
+func isThematicBreak(line []lexer.Token) bool {
+	marks := ""
+	for _, tk := range line {
+		if tk.TokenType != lexer.WS && tk.TokenType != lexer.NL {
+			marks += tk.Lit
+		}
+	}
+	return len(marks) >= 3 && strings.Contains("*-_", marks[:1]) && strings.Trim(marks, marks[:1]) == ""
+}
+
+func isListItemStart(tk lexer.Token, after lexer.Token) bool {
+	switch {
+	case tk.TokenType == lexer.TEXT && (tk.Lit == "-" || isOrderedMarker(tk.Lit)):
+	case tk.TokenType == lexer.ILLEGAL && (tk.Lit == "*" || tk.Lit == "+"):
+	case tk.TokenType == lexer.TEXT && isDigits(tk.Lit) && after.TokenType == lexer.RIGHTPRN:
+		return true
+	default:
+		return false
+	}
+	return after.TokenType == lexer.WS || after.TokenType == lexer.NL || after.TokenType == lexer.EOF
+}
+
+func indexOf(tokens []lexer.Token, tk lexer.Token) int {
+	for i := range tokens {
+		if tokens[i] == tk {
+			return i
+		}
+	}
+	return -1
+}
+
+func (p *Parser) parseListMarker(indent int, tk lexer.Token) (int, bool) {
+	marker := []lexer.Token{tk}
+	isMarker := false
+
+	switch tk.TokenType {
+	case lexer.TEXT:
+		isMarker = tk.Lit == "-" || isOrderedMarker(tk.Lit)
+		if !isMarker && isDigits(tk.Lit) {
+			if after := p.next(); after.TokenType == lexer.RIGHTPRN {
+				marker = append(marker, after)
+				isMarker = true
+			} else {
+				p.backup(false, after)
+			}
+		}
+	case lexer.ILLEGAL:
+		isMarker = tk.Lit == "*" || tk.Lit == "+"
+	}
+
+	if !isMarker {
+		p.backup(false, marker[1:]...)
+		return 0, false
+	}
+
+	width := 0
+	for _, m := range marker {
+		width += m.Length
+	}
+
+	after := p.next()
+	p.backup(false, append(marker[1:], after)...)
+
+	switch after.TokenType {
+	case lexer.WS:
+		spaces := indentWidth(after.Lit)
+		if spaces > tabStop {
+			spaces = 1
+		}
+		return indent + width + spaces, true
+	case lexer.NL, lexer.EOF:
+		return indent + width + 1, true
+	}
+	return 0, false
+}
+
+func isOrderedMarker(lit string) bool {
+	return len(lit) > 1 && len(lit) <= 10 && strings.HasSuffix(lit, ".") && isDigits(lit[:len(lit)-1])
+}
+
+func isDigits(lit string) bool {
+	if lit == "" || len(lit) > 9 {
+		return false
+	}
+	for _, ch := range lit {
+		if ch < '0' || ch > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func indentWidth(ws string) int {
+	width := 0
+	for _, ch := range ws {
+		if ch == '\t' {
+			width += tabStop - width%tabStop
+		} else {
+			width++
+		}
+	}
+	return width
+}
+
+func stripIndent(line string, n int) string {
+	width := 0
+	for i, ch := range line {
+		if width >= n {
+			return line[i:]
+		}
+
+		switch ch {
+		case ' ':
+			width++
+		case '\t':
+			width += tabStop - width%tabStop
+			if width > n {
+				return strings.Repeat(" ", width-n) + line[i+1:]
+			}
+		default:
+			return line[i:]
+		}
+	}
+	return ""
+}
+
+func literal(tokens []lexer.Token) string {
+	var sb strings.Builder
+	for _, tk := range tokens {
+		sb.WriteString(tk.Lit)
+	}
+	return sb.String()
+}
+
+func isBlank(line []lexer.Token) bool {
+	for _, tk := range line {
+		if tk.TokenType != lexer.WS && tk.TokenType != lexer.NL {
+			return false
+		}
+	}
+	return true
+}
+
+func trimNewLine(s string) string {
+	if strings.HasSuffix(s, "\r\n") {
+		return s[:len(s)-2]
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(s, "\n"), "\r")
+}
+
+func (p *Parser) endOf(tokens []lexer.Token) (int, int, int) {
+	if trimmed := trimNewLines(tokens); len(trimmed) > 0 {
+		tk := trimmed[len(trimmed)-1]
+		return tk.LineNr, p.endColumn(tk), tk.EndOffset
+	}
+	return tokens[0].LineNr, p.column(tokens[0]), tokens[0].Offset
+}
+
+// trimNewLines drops the line endings at the end of a block, which endOf
+// leaves out of its range.
+func trimNewLines(tokens []lexer.Token) []lexer.Token {
+	for len(tokens) > 0 && tokens[len(tokens)-1].TokenType == lexer.NL {
+		tokens = tokens[:len(tokens)-1]
+	}
+	return tokens
+}
+
+func isHeadingStart(tk lexer.Token, after lexer.Token) bool {
+	if tk.TokenType != lexer.HASH || tk.Length > len(headings) {
+		return false
+	}
+	return after.TokenType == lexer.WS || after.TokenType == lexer.NL || after.TokenType == lexer.EOF
+}
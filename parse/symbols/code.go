@@ -0,0 +1,220 @@
+package symbols
+
+import (
+	"strings"
+
+	"github.com/siasmey/markdown/parse/lexer"
+)
+
+func isFence(tk lexer.Token) bool {
+	return (tk.TokenType == lexer.TICK || tk.TokenType == lexer.TILDE) && tk.Length >= 3
+}
+
+func (p *Parser) parseFencedCode(line []lexer.Token, indent int) (Symbol, bool) {
+	fence := line[len(line)-1]
+	opening, _ := p.readLine()
+	opening = append(line, opening...)
+
+	info := ""
+	for _, tk := range opening[len(line):] {
+		if fence.TokenType == lexer.TICK && tk.TokenType == lexer.TICK {
+			p.backup(false, opening...)
+			return Symbol{}, false
+		}
+		if tk.TokenType != lexer.NL {
+			info += tk.Lit
+		}
+	}
+
+	tokens := opening[len(line)-1:]
+	content := ""
+	closed := false
+
+	for {
+		next, more := p.readLine()
+		if len(next) == 0 {
+			break
+		}
+
+		tokens = append(tokens, next...)
+		if closed = isClosingFence(next, fence); closed {
+			break
+		}
+		content += stripIndent(literal(next), indent)
+
+		if !more {
+			break
+		}
+	}
+
+	if !closed {
+		p.diagnose(WARNING, CodeUnterminatedFence, p.tokenRange(fence, fence), "unterminated code fence, missing closing %s", fence.Lit)
+	}
+
+	p.paragraph = false
+	lineEnd, charEnd, endOffset := p.endOf(tokens)
+
+	return Symbol{
+		Type:      CODEBLOCK,
+		Lit:       literal(trimNewLines(tokens)),
+		Value:     trimNewLine(content),
+		Language:  language(unescape(info)),
+		LineNo:    fence.LineNr,
+		EndLineNo: lineEnd,
+		CharStart: p.column(fence),
+		CharEnd:   charEnd,
+		Offset:    fence.Offset,
+		EndOffset: endOffset,
+	}, true
+}
+
+func isClosingFence(line []lexer.Token, fence lexer.Token) bool {
+	i := 0
+	if line[i].TokenType == lexer.WS {
+		if indentWidth(line[i].Lit) >= tabStop {
+			return false
+		}
+		i++
+	}
+
+	if i >= len(line) || line[i].TokenType != fence.TokenType || line[i].Length < fence.Length {
+		return false
+	}
+	return isBlank(line[i+1:])
+}
+
+func language(info string) string {
+	fields := strings.Fields(info)
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[0]
+}
+
+func (p *Parser) parseIndentedCode(line []lexer.Token, indent int) Symbol {
+	rest, more := p.readLine()
+	tokens := append(line, rest...)
+	content := stripIndent(literal(tokens), indent)
+	blanks := []lexer.Token{}
+	blankLines := ""
+
+	for more {
+		var next []lexer.Token
+		next, more = p.readLine()
+		if len(next) == 0 {
+			break
+		}
+
+		if isBlank(next) {
+			blanks = append(blanks, next...)
+			blankLines += stripIndent(literal(next), indent)
+			continue
+		}
+
+		if next[0].TokenType != lexer.WS || indentWidth(next[0].Lit) < indent {
+			p.backup(true, next...)
+			break
+		}
+
+		tokens = append(append(tokens, blanks...), next...)
+		content += blankLines + stripIndent(literal(next), indent)
+		blanks = blanks[:0]
+		blankLines = ""
+	}
+
+	p.paragraph = false
+	lineEnd, charEnd, endOffset := p.endOf(tokens)
+
+	return Symbol{
+		Type:      CODEBLOCK,
+		Lit:       literal(trimNewLines(tokens)),
+		Value:     trimNewLine(content),
+		LineNo:    tokens[0].LineNr,
+		EndLineNo: lineEnd,
+		CharStart: p.column(tokens[0]),
+		CharEnd:   charEnd,
+		Offset:    tokens[0].Offset,
+		EndOffset: endOffset,
+	}
+}
+
+func (p *Parser) parseCodeSpan(open lexer.Token) (Symbol, bool) {
+	span, ok := p.scanCodeSpan(open)
+	if !ok {
+		return Symbol{}, false
+	}
+
+	closing := span[len(span)-1]
+	content := ""
+	for _, tk := range span[:len(span)-1] {
+		if tk.TokenType == lexer.NL {
+			content += " "
+		} else {
+			content += tk.Lit
+		}
+	}
+
+	return Symbol{
+		Type:      CODESPAN,
+		Lit:       open.Lit + literal(span),
+		Value:     trimCodeSpan(content),
+		LineNo:    open.LineNr,
+		EndLineNo: closing.LineNr,
+		CharStart: p.column(open),
+		CharEnd:   p.endColumn(closing),
+		Offset:    open.Offset,
+		EndOffset: closing.EndOffset,
+	}, true
+}
+
+// scanCodeSpan consumes tokens up to and including the backtick run closing
+// open. The span may cover several lines but never leaves the paragraph; when
+// no closing run is found every token is handed back to the parser.
+func (p *Parser) scanCodeSpan(open lexer.Token) ([]lexer.Token, bool) {
+	span := []lexer.Token{}
+
+	for {
+		tk := p.next()
+		if tk.TokenType == lexer.EOF {
+			break
+		}
+		span = append(span, tk)
+
+		if tk.TokenType == lexer.TICK && tk.Length == open.Length {
+			return span, true
+		}
+		if tk.TokenType == lexer.NL && p.endsParagraph(&span) {
+			break
+		}
+	}
+
+	p.backup(false, span...)
+	return nil, false
+}
+
+func (p *Parser) endsParagraph(span *[]lexer.Token) bool {
+	if last := (*span)[len(*span)-1]; last == p.headingEnd || last == textEnd {
+		return true
+	}
+	tk := p.next()
+	indent := 0
+	if tk.TokenType == lexer.WS {
+		*span = append(*span, tk)
+		indent = indentWidth(tk.Lit)
+		tk = p.next()
+	}
+	after := p.next()
+	p.backup(false, tk, after)
+
+	if indent >= tabStop {
+		return false
+	}
+	return tk.TokenType == lexer.NL || tk.TokenType == lexer.EOF || isFence(tk) || isHeadingStart(tk, after)
+}
+
+func trimCodeSpan(content string) string {
+	if len(content) >= 2 && content[0] == ' ' && content[len(content)-1] == ' ' && strings.Trim(content, " ") != "" {
+		return content[1 : len(content)-1]
+	}
+	return content
+}
@@ -0,0 +1,324 @@
+package symbols
+
+import "testing"
+
+func TestParseShouldReturnFencedCodeBlock(t *testing.T) {
+	input := "```bash\n# comment\n[[x]]\n```"
+
+	res, err := Parse(input)
+	if len(res.CodeBlocks) != 1 {
+		failMessageInt(t, input, len(res.CodeBlocks), err, 1)
+	}
+
+	check := res.CodeBlocks[0]
+	if check.Type != CODEBLOCK {
+		failMessageType(t, input, check.Type, err, CODEBLOCK)
+	}
+	if check.Language != "bash" {
+		failMessageString(t, input, check.Language, err, "bash")
+	}
+	if check.Value != "# comment\n[[x]]" {
+		failMessageString(t, input, check.Value, err, "# comment\n[[x]]")
+	}
+	if check.Lit != input {
+		failMessageString(t, input, check.Lit, err, input)
+	}
+}
+
+func TestParseShouldReturnFencedCodeBlockRange(t *testing.T) {
+	input := "text\n```go\nfunc main() {}\n```\nmore"
+
+	res, err := Parse(input)
+	check := res.CodeBlocks[0]
+
+	if check.LineNo != 1 {
+		failMessageInt(t, input, check.LineNo, err, 1)
+	}
+	if check.EndLineNo != 3 {
+		failMessageInt(t, input, check.EndLineNo, err, 3)
+	}
+	if check.CharStart != 1 {
+		failMessageInt(t, input, check.CharStart, err, 1)
+	}
+	if check.CharEnd != 4 {
+		failMessageInt(t, input, check.CharEnd, err, 4)
+	}
+}
+
+func TestParseShouldSuppressSymbolsInFencedCode(t *testing.T) {
+	tests := map[string]string{
+		"Backticks":    "```\n# not a title\n[[not-a-link]]\n#[[not-a-tag]]\n[a](http://b.com)\n```",
+		"Tildes":       "~~~\n# not a title\n[[not-a-link]]\n#[[not-a-tag]]\n[a](http://b.com)\n~~~",
+		"LongFence":    "`````\n```\n# not a title\n```\n[[not-a-link]]\n#[[not-a-tag]]\n[a](http://b.com)\n`````",
+		"Indented":     "   ```\n# not a title\n[[not-a-link]]\n#[[not-a-tag]]\n[a](http://b.com)\n   ```",
+		"InfoString":   "```python title=\"x.py\"\n# not a title\n[[not-a-link]]\n#[[not-a-tag]]\n[a](http://b.com)\n```",
+		"Unterminated": "```\n# not a title\n[[not-a-link]]\n#[[not-a-tag]]\n[a](http://b.com)\n",
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			res, err := Parse(input)
+			if len(res.Headers) != 0 {
+				failMessageInt(t, input, len(res.Headers), err, 0)
+			}
+			if len(res.WikiLinks) != 0 {
+				failMessageInt(t, input, len(res.WikiLinks), err, 0)
+			}
+			if len(res.Tags) != 0 {
+				failMessageInt(t, input, len(res.Tags), err, 0)
+			}
+			if len(res.Links) != 0 {
+				failMessageInt(t, input, len(res.Links), err, 0)
+			}
+			if len(res.CodeBlocks) != 1 {
+				failMessageInt(t, input, len(res.CodeBlocks), err, 1)
+			}
+		})
+	}
+}
+
+func TestParseShouldTrimUnterminatedFenceLit(t *testing.T) {
+	tests := []string{"```\ncode\n\n", "```\ncode\r\n\r\n\r\n", "```go\n", "    code\n\n\n"}
+
+	for _, input := range tests {
+		res, err := Parse(input)
+		if len(res.CodeBlocks) != 1 {
+			failMessageInt(t, input, len(res.CodeBlocks), err, 1)
+			continue
+		}
+		code := res.CodeBlocks[0]
+		if expected := input[code.Offset:code.EndOffset]; code.Lit != expected {
+			failMessageString(t, input, code.Lit, err, expected)
+		}
+	}
+}
+
+func TestParseShouldReturnSymbolsAfterFencedCode(t *testing.T) {
+	input := "```\n# comment\n```\n# Title\n[[after]]"
+
+	res, err := Parse(input)
+	if res.Title.Value != "Title" {
+		failMessageString(t, input, res.Title.Value, err, "Title")
+	}
+	if res.WikiLinks[0].Value != "after" {
+		failMessageString(t, input, res.WikiLinks[0].Value, err, "after")
+	}
+}
+
+func TestParseShouldNotCloseFenceWithShorterOrDifferentFence(t *testing.T) {
+	input := "````\n```\n~~~~\n[[inside]]\n````\n[[outside]]"
+
+	res, err := Parse(input)
+	if len(res.WikiLinks) != 1 || res.WikiLinks[0].Value != "outside" {
+		failMessageInt(t, input, len(res.WikiLinks), err, 1)
+	}
+	if res.CodeBlocks[0].Value != "```\n~~~~\n[[inside]]" {
+		failMessageString(t, input, res.CodeBlocks[0].Value, err, "```\n~~~~\n[[inside]]")
+	}
+}
+
+func TestParseShouldNotOpenBacktickFenceWithBacktickInInfo(t *testing.T) {
+	input := "``` a`b\n# Title"
+
+	res, err := Parse(input)
+	if len(res.CodeBlocks) != 0 {
+		failMessageInt(t, input, len(res.CodeBlocks), err, 0)
+	}
+	if res.Title.Value != "Title" {
+		failMessageString(t, input, res.Title.Value, err, "Title")
+	}
+}
+
+func TestParseShouldStripFenceIndentFromContent(t *testing.T) {
+	input := "  ```\n  a\n    b\nc\n  ```"
+	expected := "a\n  b\nc"
+
+	res, err := Parse(input)
+	if res.CodeBlocks[0].Value != expected {
+		failMessageString(t, input, res.CodeBlocks[0].Value, err, expected)
+	}
+}
+
+func TestParseShouldReturnIndentedCodeBlock(t *testing.T) {
+	input := "text\n\n    # code [[x]]\n\n\tmore\nafter [[y]]"
+
+	res, err := Parse(input)
+	if len(res.CodeBlocks) != 1 {
+		failMessageInt(t, input, len(res.CodeBlocks), err, 1)
+	}
+
+	check := res.CodeBlocks[0]
+	if check.Value != "# code [[x]]\n\nmore" {
+		failMessageString(t, input, check.Value, err, "# code [[x]]\n\nmore")
+	}
+	if check.LineNo != 2 {
+		failMessageInt(t, input, check.LineNo, err, 2)
+	}
+	if check.EndLineNo != 4 {
+		failMessageInt(t, input, check.EndLineNo, err, 4)
+	}
+	if len(res.Headers) != 0 {
+		failMessageInt(t, input, len(res.Headers), err, 0)
+	}
+	if len(res.WikiLinks) != 1 || res.WikiLinks[0].Value != "y" {
+		failMessageInt(t, input, len(res.WikiLinks), err, 1)
+	}
+}
+
+func TestParseShouldNotStartIndentedCodeInParagraph(t *testing.T) {
+	input := "text\n    [[continuation]]"
+
+	res, err := Parse(input)
+	if len(res.CodeBlocks) != 0 {
+		failMessageInt(t, input, len(res.CodeBlocks), err, 0)
+	}
+	if len(res.WikiLinks) != 1 {
+		failMessageInt(t, input, len(res.WikiLinks), err, 1)
+	}
+}
+
+func TestParseShouldNotStartIndentedCodeInListItem(t *testing.T) {
+	input := "- item\n\n    - child [[nested]]\n\n1. first\n\n    [[ordered]]"
+
+	res, err := Parse(input)
+	if len(res.CodeBlocks) != 0 {
+		failMessageInt(t, input, len(res.CodeBlocks), err, 0)
+	}
+	if len(res.WikiLinks) != 2 {
+		failMessageInt(t, input, len(res.WikiLinks), err, 2)
+	}
+}
+
+func TestParseShouldStartIndentedCodeAfterList(t *testing.T) {
+	input := "- item\n\ntext\n\n    [[code]]"
+
+	res, err := Parse(input)
+	if len(res.CodeBlocks) != 1 {
+		failMessageInt(t, input, len(res.CodeBlocks), err, 1)
+	}
+	if len(res.WikiLinks) != 0 {
+		failMessageInt(t, input, len(res.WikiLinks), err, 0)
+	}
+}
+
+func TestParseShouldStartIndentedCodeAfterHeading(t *testing.T) {
+	input := "# Title\n    [[code]]"
+
+	res, err := Parse(input)
+	if len(res.CodeBlocks) != 1 {
+		failMessageInt(t, input, len(res.CodeBlocks), err, 1)
+	}
+}
+
+func TestParseShouldReturnCodeSpan(t *testing.T) {
+	tests := map[string]struct {
+		input string
+		want  string
+	}{
+		"Simple":          {"use `[[x]]` here", "[[x]]"},
+		"DoubleBackticks": {"`` a ` b ``", "a ` b"},
+		"StripOneSpace":   {"`  [x](y)  `", " [x](y) "},
+		"OnlySpaces":      {"`   `", "   "},
+		"MultiLine":       {"`#[[a]]\nb`", "#[[a]] b"},
+		"Unicode":         {"`ünï ⌘`", "ünï ⌘"},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			res, err := Parse(tc.input)
+			if len(res.CodeSpans) != 1 {
+				failMessageInt(t, tc.input, len(res.CodeSpans), err, 1)
+			}
+			check := res.CodeSpans[0]
+			if check.Type != CODESPAN {
+				failMessageType(t, tc.input, check.Type, err, CODESPAN)
+			}
+			if check.Value != tc.want {
+				failMessageString(t, tc.input, check.Value, err, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseShouldReturnCodeSpanPosition(t *testing.T) {
+	input := "see ``[[x]]``!"
+
+	res, err := Parse(input)
+	check := res.CodeSpans[0]
+
+	if check.Lit != "``[[x]]``" {
+		failMessageString(t, input, check.Lit, err, "``[[x]]``")
+	}
+	if check.CharStart != 5 {
+		failMessageInt(t, input, check.CharStart, err, 5)
+	}
+	if check.CharEnd != 14 {
+		failMessageInt(t, input, check.CharEnd, err, 14)
+	}
+}
+
+func TestParseShouldSuppressSymbolsInCodeSpan(t *testing.T) {
+	input := "`[[wiki]]` `#[[tag]]` ``[a](http://b.com)`` [[real]]"
+
+	res, err := Parse(input)
+	if len(res.WikiLinks) != 1 || res.WikiLinks[0].Value != "real" {
+		failMessageInt(t, input, len(res.WikiLinks), err, 1)
+	}
+	if len(res.Tags) != 0 {
+		failMessageInt(t, input, len(res.Tags), err, 0)
+	}
+	if len(res.Links) != 0 {
+		failMessageInt(t, input, len(res.Links), err, 0)
+	}
+	if len(res.CodeSpans) != 3 {
+		failMessageInt(t, input, len(res.CodeSpans), err, 3)
+	}
+}
+
+func TestParseShouldTreatUnmatchedBackticksAsText(t *testing.T) {
+	input := "a `` b ` [[still-a-link]]"
+
+	res, err := Parse(input)
+	if len(res.CodeSpans) != 0 {
+		failMessageInt(t, input, len(res.CodeSpans), err, 0)
+	}
+	if len(res.WikiLinks) != 1 {
+		failMessageInt(t, input, len(res.WikiLinks), err, 1)
+	}
+}
+
+func TestParseShouldNotExtendCodeSpanPastParagraph(t *testing.T) {
+	input := "a `b\n\n[[link]] c`"
+
+	res, err := Parse(input)
+	if len(res.CodeSpans) != 0 {
+		failMessageInt(t, input, len(res.CodeSpans), err, 0)
+	}
+	if len(res.WikiLinks) != 1 {
+		failMessageInt(t, input, len(res.WikiLinks), err, 1)
+	}
+}
+
+func TestParseShouldNotExtendCodeSpanIntoHeading(t *testing.T) {
+	input := "a `b\n# Title `"
+
+	res, err := Parse(input)
+	if len(res.CodeSpans) != 0 {
+		failMessageInt(t, input, len(res.CodeSpans), err, 0)
+	}
+	if res.Title.Value != "Title `" {
+		failMessageString(t, input, res.Title.Value, err, "Title `")
+	}
+}
+
+func TestParseShouldKeepCodeSpanInsideLinkText(t *testing.T) {
+	input := "[`[[x]]`](http://example.com)"
+
+	res, err := Parse(input)
+	if len(res.WikiLinks) != 0 {
+		failMessageInt(t, input, len(res.WikiLinks), err, 0)
+	}
+	if len(res.Links) != 1 || res.Links[0].Value != "http://example.com" {
+		failMessageInt(t, input, len(res.Links), err, 1)
+	}
+}
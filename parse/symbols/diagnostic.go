@@ -0,0 +1,83 @@
+package symbols
+
+import (
+	"fmt"
+
+	"github.com/siasmey/markdown/parse/lexer"
+)
+
+type Severity string
+
+const (
+	ERROR   Severity = "Error"
+	WARNING Severity = "Warning"
+)
+
+const (
+	CodeUnterminatedWikiLink    = "unterminated-wikilink"
+	CodeUnterminatedTag         = "unterminated-tag"
+	CodeUnterminatedLink        = "unterminated-link"
+	CodeUnterminatedFence       = "unterminated-fence"
+	CodeUnterminatedFrontMatter = "unterminated-front-matter"
+	CodeInvalidFrontMatter      = "invalid-front-matter"
+)
+
+type Position struct {
+	Line   int
+	Column int
+}
+
+type Range struct {
+	Start Position
+	End   Position
+}
+
+type Diagnostic struct {
+	Severity Severity
+	Code     string
+	Message  string
+	Range    Range
+}
+
+func (d Diagnostic) Error() string {
+	return fmt.Sprintf("line %d column %d: %s", d.Range.Start.Line+1, d.Range.Start.Column, d.Message)
+}
+
+type Diagnostics []Diagnostic
+
+func (d Diagnostics) Error() string {
+	switch len(d) {
+	case 0:
+		return "no diagnostics"
+	case 1:
+		return d[0].Error()
+	}
+	return fmt.Sprintf("%s (and %d more)", d[0].Error(), len(d)-1)
+}
+
+// Err returns the diagnostics as an error when at least one of them is an
+// error rather than a warning.
+func (d Diagnostics) Err() error {
+	for _, diag := range d {
+		if diag.Severity == ERROR {
+			return d
+		}
+	}
+	return nil
+}
+
+func (p *Parser) tokenRange(first lexer.Token, last lexer.Token) Range {
+	return Range{
+		Start: Position{Line: first.LineNr, Column: p.column(first)},
+		End:   Position{Line: last.LineNr, Column: p.endColumn(last)},
+	}
+}
+
+func (p *Parser) diagnose(severity Severity, code string, rng Range, format string, args ...interface{}) {
+	p.diagnostics = append(p.diagnostics, Diagnostic{
+		Severity: severity,
+		Code:     code,
+		Message:  fmt.Sprintf(format, args...),
+		Range:    rng,
+	})
+}
@@ -0,0 +1,122 @@
+package symbols
+
+import "testing"
+
+func TestParseShouldReturnDiagnostics(t *testing.T) {
+	tests := map[string]struct {
+		input    string
+		severity Severity
+		code     string
+		want     Range
+	}{
+		"WikiLink":         {"text [[foo", ERROR, CodeUnterminatedWikiLink, Range{Position{0, 6}, Position{0, 8}}},
+		"Embed":            {"x\n![[foo", ERROR, CodeUnterminatedWikiLink, Range{Position{1, 1}, Position{1, 4}}},
+		"Link":             {"[a](b", WARNING, CodeUnterminatedLink, Range{Position{0, 4}, Position{0, 5}}},
+		"LinkTitle":        {"x [a](b \"t\n\ny", WARNING, CodeUnterminatedLink, Range{Position{0, 6}, Position{0, 7}}},
+		"LinkWords":        {"[a](b c\nd", WARNING, CodeUnterminatedLink, Range{Position{0, 4}, Position{0, 5}}},
+		"LinkHeading":      {"![a](b\n# H", WARNING, CodeUnterminatedLink, Range{Position{0, 5}, Position{0, 6}}},
+		"Tag":              {"a #[[multi word\nb", ERROR, CodeUnterminatedTag, Range{Position{0, 3}, Position{0, 5}}},
+		"Fence":            {"text\n```go\ncode", WARNING, CodeUnterminatedFence, Range{Position{1, 1}, Position{1, 4}}},
+		"FrontMatter":      {"---\ntitle: x\n", WARNING, CodeUnterminatedFrontMatter, Range{Position{0, 1}, Position{0, 4}}},
+		"JSONFrontMatter":  {"{\n  \"title\": \"x\"\n", WARNING, CodeUnterminatedFrontMatter, Range{Position{0, 1}, Position{0, 2}}},
+		"InvalidYAML":      {"---\ntitle: x\n  bad: y\n---", ERROR, CodeInvalidFrontMatter, Range{Position{2, 3}, Position{2, 4}}},
+		"InvalidTOML":      {"+++\ntitle = \n+++", ERROR, CodeInvalidFrontMatter, Range{Position{1, 9}, Position{1, 10}}},
+		"InvalidJSON":      {"{\n  \"a\": 1\n  \"b\": 2\n}", ERROR, CodeInvalidFrontMatter, Range{Position{2, 3}, Position{2, 4}}},
+		"WikiLinkUnicode":  {"日本 [[x", ERROR, CodeUnterminatedWikiLink, Range{Position{0, 8}, Position{0, 10}}},
+		"TildeFence":       {"~~~~\ncode\n~~~", WARNING, CodeUnterminatedFence, Range{Position{0, 1}, Position{0, 5}}},
+		"WikiLinkLastLine": {"# Title\n\n[[", ERROR, CodeUnterminatedWikiLink, Range{Position{2, 1}, Position{2, 3}}},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			res, err := Parse(tc.input)
+			if len(res.Diagnostics) != 1 {
+				t.Fatalf("Parse(%q) diagnostics = %v, expected 1", tc.input, res.Diagnostics)
+			}
+
+			diag := res.Diagnostics[0]
+			if diag.Severity != tc.severity || diag.Code != tc.code {
+				t.Fatalf("Parse(%q) diagnostic = %s %s, expected %s %s", tc.input, diag.Severity, diag.Code, tc.severity, tc.code)
+			}
+			if diag.Range != tc.want {
+				t.Fatalf("Parse(%q) diagnostic range = %v, expected %v", tc.input, diag.Range, tc.want)
+			}
+			if diag.Message == "" {
+				t.Fatalf("Parse(%q) diagnostic has no message", tc.input)
+			}
+
+			if err != nil {
+				t.Fatalf("Parse(%q) returned error %v, expected diagnostics only", tc.input, err)
+			}
+			if isError := res.Diagnostics.Err() != nil; isError != (tc.severity == ERROR) {
+				t.Fatalf("Parse(%q) Diagnostics.Err() = %v, expected an error only for error diagnostics", tc.input, res.Diagnostics.Err())
+			}
+		})
+	}
+}
+
+func TestParseShouldNotReturnDiagnostics(t *testing.T) {
+	input := "---\ntitle: x\n---\n# Title\n[[link]] #[[tag]] #tag\n```\ncode\n```\n[a](b) [c](d e) [f](<g\nh)"
+
+	res, err := Parse(input)
+	if len(res.Diagnostics) != 0 || err != nil {
+		t.Fatalf("Parse(%q) diagnostics = %v, %v, expected none", input, res.Diagnostics, err)
+	}
+}
+
+func TestParseShouldReportEveryUnterminatedLinkOnce(t *testing.T) {
+	tests := map[string]int{
+		"[a](b [c](d":      2,
+		"[x [a](b":         1,
+		"[a](b \"[c](d \"": 2,
+		"[a](b\n\n[c](d)":  1,
+	}
+
+	for input, want := range tests {
+		res, _ := Parse(input)
+		if len(res.Diagnostics) != want {
+			t.Errorf("Parse(%q) diagnostics = %v, expected %d", input, res.Diagnostics, want)
+		}
+	}
+}
+
+func TestParseShouldRecoverAfterUnterminatedWikiLink(t *testing.T) {
+	input := "[[open\n# Title\n[[closed]] #tag [a](b)"
+
+	res, err := Parse(input)
+	if err != nil || res.Diagnostics.Err() == nil {
+		t.Fatalf("Parse(%q) = %v, %v, expected an error diagnostic only", input, res.Diagnostics, err)
+	}
+	if res.Title.Value != "Title" {
+		failMessageString(t, input, res.Title.Value, err, "Title")
+	}
+	if len(res.WikiLinks) != 1 || res.WikiLinks[0].Value != "closed" {
+		failMessageInt(t, input, len(res.WikiLinks), err, 1)
+	}
+	if len(res.Tags) != 1 || len(res.Links) != 1 {
+		failMessageInt(t, input, len(res.Tags), err, 1)
+	}
+}
+
+func TestParseShouldRecoverAfterUnterminatedTag(t *testing.T) {
+	input := "#[[open [[link]]\n# Title"
+
+	res, err := Parse(input)
+	if len(res.Tags) != 0 {
+		failMessageInt(t, input, len(res.Tags), err, 0)
+	}
+	if len(res.WikiLinks) != 1 || res.WikiLinks[0].Value != "link" {
+		failMessageInt(t, input, len(res.WikiLinks), err, 1)
+	}
+	if res.Title.Value != "Title" {
+		failMessageString(t, input, res.Title.Value, err, "Title")
+	}
+}
+
+func TestDiagnosticsShouldFormatError(t *testing.T) {
+	res, _ := Parse("[[a\n[[b")
+	want := "line 1 column 1: unterminated wikilink, missing ]] (and 1 more)"
+	if err := res.Diagnostics.Err(); err == nil || err.Error() != want {
+		t.Fatalf("Parse diagnostics error = %v, expected %q", err, want)
+	}
+}
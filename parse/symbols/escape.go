@@ -0,0 +1,74 @@
+package symbols
+
+import (
+	"html"
+	"strings"
+
+	"github.com/siasmey/markdown/parse/lexer"
+)
+
+// scanEscape reads the token after a backslash and returns it if the
+// backslash escapes it. Otherwise the token is handed back.
+func (p *Parser) scanEscape(backslash lexer.Token) (lexer.Token, bool) {
+	if backslash.Lit != "\\" {
+		return lexer.Token{}, false
+	}
+
+	tk := p.next()
+	if tk.TokenType == lexer.WS || tk.TokenType == lexer.NL || tk.Lit == "" || !isASCIIPunct(tk.Lit[0]) {
+		p.backup(false, tk)
+		return lexer.Token{}, false
+	}
+	return tk, true
+}
+
+func isASCIIPunct(ch byte) bool {
+	return strings.IndexByte("!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~", ch) >= 0
+}
+
+// unescape resolves the backslash escapes and the entity and numeric
+// character references of a link destination or code block info string.
+func unescape(s string) string {
+	if !strings.ContainsAny(s, "\\&") {
+		return s
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		if ch == '\\' && i+1 < len(s) && isASCIIPunct(s[i+1]) {
+			i++
+			sb.WriteByte(s[i])
+			continue
+		}
+		if ch == '&' {
+			if ref, ok := reference(s[i:]); ok {
+				sb.WriteString(html.UnescapeString(ref))
+				i += len(ref) - 1
+				continue
+			}
+		}
+		sb.WriteByte(ch)
+	}
+	return sb.String()
+}
+
+// reference returns the character reference s starts with, &name; or &#digits;
+// or &#xhex;, if html knows it.
+func reference(s string) (string, bool) {
+	end := strings.IndexByte(s, ';')
+	if end < 2 || end > 32 {
+		return "", false
+	}
+
+	for i := 1; i < end; i++ {
+		if ch := s[i]; !isASCIILetter(ch) && !isASCIIDigit(ch) && (ch != '#' || i != 1) {
+			return "", false
+		}
+	}
+	// html also decodes the legacy names a longer name starts with, as in
+	// &notit; for &not, and leaves the rest and the semicolon behind.
+	ref := s[:end+1]
+	decoded := html.UnescapeString(ref)
+	return ref, decoded != ref && (decoded == ";" || !strings.HasSuffix(decoded, ";"))
+}
@@ -0,0 +1,58 @@
+package symbols
+
+import "testing"
+
+func TestParseShouldNotOpenEscapedSymbols(t *testing.T) {
+	input := "\\#tag \\[[x]] \\[a](b) \\`c` #ok"
+
+	res, err := Parse(input)
+	if len(res.Tags) != 1 || res.Tags[0].Value != "ok" {
+		failMessageInt(t, input, len(res.Tags), err, 1)
+	}
+	if n := len(res.WikiLinks) + len(res.Links) + len(res.Images) + len(res.CodeSpans); n != 0 {
+		failMessageInt(t, input, n, err, 0)
+	}
+}
+
+func TestParseShouldKeepBackslashBeforeOtherCharacters(t *testing.T) {
+	input := "\\a #tag \\\n[[x]]"
+
+	res, err := Parse(input)
+	if len(res.Tags) != 1 || len(res.WikiLinks) != 1 {
+		failMessageInt(t, input, len(res.Tags)+len(res.WikiLinks), err, 2)
+	}
+}
+
+func TestParseShouldUnescapeDestinationsAndLanguages(t *testing.T) {
+	tests := map[string]struct {
+		input string
+		want  string
+	}{
+		"EscapedParen":     {"[a](u\\(v)", "u(v"},
+		"Entity":           {"[a](&amp;x)", "&x"},
+		"NumericReference": {"[a](&#35;x)", "#x"},
+		"UnknownEntity":    {"[a](&nosuch;x)", "&nosuch;x"},
+		"LegacyPrefix":     {"[a](&notit;)", "&notit;"},
+		"Definition":       {"[a]\n\n[a]: \\*u\\*", "*u*"},
+		"Language":         {"```g\\+\\+ x\ny\n```", "g++"},
+		"LanguageEntity":   {"```f&ouml;o\ny\n```", "föo"},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			res, err := Parse(tc.input)
+			var got string
+			switch {
+			case len(res.Links) == 1:
+				got = res.Links[0].Value
+			case len(res.CodeBlocks) == 1:
+				got = res.CodeBlocks[0].Language
+			default:
+				t.Fatalf("Parse(%q) returned no link or code block", tc.input)
+			}
+			if got != tc.want {
+				failMessageString(t, tc.input, got, err, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,329 @@
+package symbols
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/siasmey/markdown/internal/frontmatter"
+	"github.com/siasmey/markdown/internal/frontmatter/json"
+	"github.com/siasmey/markdown/internal/frontmatter/toml"
+	"github.com/siasmey/markdown/internal/frontmatter/yaml"
+	"github.com/siasmey/markdown/parse/lexer"
+)
+
+type FrontMatterFormat string
+
+const (
+	YAML FrontMatterFormat = "yaml"
+	TOML FrontMatterFormat = "toml"
+	JSON FrontMatterFormat = "json"
+)
+
+type FrontMatter struct {
+	Format    FrontMatterFormat
+	Raw       string
+	LineNo    int
+	EndLineNo int
+	Values    map[string]interface{}
+	Err       error
+}
+
+// FrontMatterError is the error of front matter that cannot be decoded, at a
+// one based column of a document line.
+type FrontMatterError = frontmatter.Error
+
+func (p *Parser) parseFrontMatter() (Symbol, bool) {
+	first, more := p.readLine()
+	format, ok := frontMatterFormat(first)
+	if !ok || (!more && format != JSON) {
+		p.backup(true, first...)
+		return Symbol{}, false
+	}
+
+	var tokens, inner []lexer.Token
+	var lines []string
+	var closing string
+	if format == JSON {
+		tokens, lines, ok = p.readJSONFrontMatter(first, more)
+		inner = tokens
+		closing = lines[len(lines)-1]
+	} else {
+		tokens, inner, lines, closing, ok = p.readDelimitedFrontMatter(first, format)
+	}
+	if !ok {
+		p.diagnose(WARNING, CodeUnterminatedFrontMatter, p.tokenRange(first[0], first[0]), "unterminated %s front matter", format)
+		p.backup(true, tokens...)
+		return Symbol{}, false
+	}
+
+	fm := &FrontMatter{
+		Format:    format,
+		Raw:       trimNewLine(literal(inner)),
+		LineNo:    0,
+		EndLineNo: len(lines) + 1,
+		Values:    map[string]interface{}{},
+	}
+
+	var root *frontmatter.Node
+	var err error
+	switch format {
+	case YAML:
+		root, err = yaml.Decode(lines, 1)
+	case TOML:
+		root, err = toml.Decode(lines, 1)
+	case JSON:
+		fm.EndLineNo = len(lines) - 1
+		root, err = json.Decode(lines, 0)
+	}
+
+	starts, texts := lineStarts(tokens), lineTexts(tokens)
+	if err == nil && root.IsMapping() {
+		fm.Values = root.ToValue().(map[string]interface{})
+		for _, sym := range frontMatterSymbols(root) {
+			sym.Offset = starts[sym.LineNo] + sym.CharStart - 1
+			sym.EndOffset = starts[sym.LineNo] + sym.CharEnd - 1
+			sym.CharStart = p.columnIn(texts[sym.LineNo], sym.CharStart)
+			sym.CharEnd = p.columnIn(texts[sym.LineNo], sym.CharEnd)
+			p.pending = append(p.pending, sym)
+		}
+	}
+	fm.Err = err
+	p.frontMatter = fm
+
+	var fmErr *FrontMatterError
+	if errors.As(err, &fmErr) {
+		text := texts[fmErr.LineNo]
+		at := Position{Line: fmErr.LineNo, Column: p.columnIn(text, fmErr.Column)}
+		end := Position{Line: fmErr.LineNo, Column: p.columnIn(text, fmErr.Column+1)}
+		p.diagnose(ERROR, CodeInvalidFrontMatter, Range{Start: at, End: end}, "invalid %s front matter: %s", format, fmErr.Msg)
+	}
+
+	charEnd := lexer.Width(strings.TrimRight(closing, " \t"), p.Encoding) + 1
+	return Symbol{
+		Type:      FRONTMATTER,
+		Lit:       trimNewLine(literal(tokens)),
+		Value:     fm.Raw,
+		LineNo:    fm.LineNo,
+		EndLineNo: fm.EndLineNo,
+		CharStart: 1,
+		CharEnd:   charEnd,
+		Offset:    tokens[0].Offset,
+		EndOffset: starts[fm.EndLineNo] + len(strings.TrimRight(closing, " \t")),
+	}, true
+}
+
+// lineStarts maps the line numbers of tokens to the offsets their lines start
+// at.
+func lineStarts(tokens []lexer.Token) map[int]int {
+	starts := map[int]int{}
+	for _, tk := range tokens {
+		if _, ok := starts[tk.LineNr]; !ok && tk.TokenType != lexer.NL {
+			starts[tk.LineNr] = tk.Offset - tk.Column + 1
+		}
+	}
+	return starts
+}
+
+func lineTexts(tokens []lexer.Token) map[int]string {
+	texts := map[int]string{}
+	for _, tk := range tokens {
+		if tk.TokenType != lexer.NL {
+			texts[tk.LineNr] += tk.Lit
+		}
+	}
+	return texts
+}
+
+// columnIn converts a byte column within text to the parser's encoding.
+func (p *Parser) columnIn(text string, column int) int {
+	if column-1 > len(text) {
+		return column - len(text) + lexer.Width(text, p.Encoding)
+	}
+	return lexer.Width(text[:column-1], p.Encoding) + 1
+}
+
+func frontMatterFormat(first []lexer.Token) (FrontMatterFormat, bool) {
+	switch {
+	case isDelimiter(first, "---"):
+		return YAML, true
+	case isDelimiter(first, "+++"):
+		return TOML, true
+	}
+
+	text := strings.TrimSpace(strings.TrimPrefix(literal(first), "\ufeff"))
+	if strings.HasPrefix(text, "{") {
+		rest := strings.TrimSpace(text[1:])
+		return JSON, rest == "" || rest[0] == '"' || rest[0] == '}'
+	}
+	return "", false
+}
+
+func (p *Parser) readDelimitedFrontMatter(first []lexer.Token, format FrontMatterFormat) ([]lexer.Token, []lexer.Token, []string, string, bool) {
+	closers := []string{"---", "..."}
+	if format == TOML {
+		closers = []string{"+++"}
+	}
+
+	tokens := first
+	inner := []lexer.Token{}
+	lines := []string{}
+
+	for {
+		line, more := p.readLine()
+		tokens = append(tokens, line...)
+
+		if isDelimiter(line, closers...) {
+			return tokens, inner, lines, trimNewLine(literal(line)), true
+		}
+		if !more {
+			return tokens, inner, lines, "", false
+		}
+
+		inner = append(inner, line...)
+		lines = append(lines, trimNewLine(literal(line)))
+	}
+}
+
+// The JSON object ends on the line where its braces balance out again, so
+// strings and nested objects have to be tracked while reading lines.
+func (p *Parser) readJSONFrontMatter(first []lexer.Token, more bool) ([]lexer.Token, []string, bool) {
+	tokens := first
+	lines := []string{}
+	depth := 0
+	line := first
+
+	for {
+		text := trimNewLine(literal(line))
+		lines = append(lines, text)
+
+		var done bool
+		depth, done = jsonDepth(text, depth)
+		if done {
+			return tokens, lines, true
+		}
+		if !more {
+			return tokens, lines, false
+		}
+
+		line, more = p.readLine()
+		tokens = append(tokens, line...)
+		if len(line) == 0 {
+			return tokens, lines, false
+		}
+	}
+}
+
+func jsonDepth(text string, depth int) (int, bool) {
+	inString := false
+	for i := 0; i < len(text); i++ {
+		switch ch := text[i]; {
+		case inString && ch == '\\':
+			i++
+		case ch == '"':
+			inString = !inString
+		case inString:
+		case ch == '{' || ch == '[':
+			depth++
+		case ch == '}' || ch == ']':
+			depth--
+			if depth == 0 {
+				return depth, true
+			}
+		}
+	}
+	return depth, false
+}
+
+func isDelimiter(line []lexer.Token, delimiters ...string) bool {
+	text := strings.TrimPrefix(trimNewLine(literal(line)), "\ufeff")
+	text = strings.TrimRight(text, " \t")
+
+	for _, delimiter := range delimiters {
+		if text == delimiter {
+			return true
+		}
+	}
+	return false
+}
+
+func frontMatterSymbols(root *frontmatter.Node) []Symbol {
+	symbols := []Symbol{}
+
+	for _, key := range root.Keys {
+		switch strings.ToLower(key) {
+		case "tags", "tag":
+			symbols = append(symbols, listSymbols(root.Fields[key], TAG, isTagSeparator)...)
+		case "aliases", "alias":
+			symbols = append(symbols, listSymbols(root.Fields[key], ALIAS, isAliasSeparator)...)
+		}
+	}
+	return symbols
+}
+
+func isTagSeparator(ch rune) bool {
+	return ch == ',' || unicode.IsSpace(ch)
+}
+
+func isAliasSeparator(ch rune) bool {
+	return ch == ','
+}
+
+func listSymbols(node *frontmatter.Node, symType SymbolType, separator func(rune) bool) []Symbol {
+	symbols := []Symbol{}
+
+	switch {
+	case node.IsSequence():
+		for _, item := range node.Items {
+			if item.Value != nil && !item.IsMapping() && !item.IsSequence() {
+				symbols = append(symbols, scalarSymbols(item, symType, func(rune) bool { return false })...)
+			}
+		}
+	case node.IsMapping() || node.Value == nil:
+	default:
+		symbols = append(symbols, scalarSymbols(node, symType, separator)...)
+	}
+	return symbols
+}
+
+func scalarSymbols(node *frontmatter.Node, symType SymbolType, separator func(rune) bool) []Symbol {
+	symbols := []Symbol{}
+	cursor := 0
+
+	for _, part := range strings.FieldsFunc(fmt.Sprint(node.Value), separator) {
+		part = strings.TrimSpace(part)
+		value := part
+		if symType == TAG {
+			value = strings.TrimPrefix(part, "#")
+		}
+		if value == "" {
+			continue
+		}
+
+		charStart := node.Column
+		charEnd := node.Column + len(node.Lit)
+		if i := strings.Index(node.Lit[cursor:], part); i >= 0 {
+			charStart = node.Column + cursor + i
+			charEnd = charStart + len(part)
+			cursor += i + len(part)
+		}
+
+		var path []string
+		if symType == TAG {
+			path = tagPath(value)
+		}
+
+		symbols = append(symbols, Symbol{
+			Type:      symType,
+			Lit:       part,
+			Value:     value,
+			Path:      path,
+			LineNo:    node.LineNo,
+			EndLineNo: node.LineNo,
+			CharStart: charStart,
+			CharEnd:   charEnd,
+		})
+	}
+	return symbols
+}
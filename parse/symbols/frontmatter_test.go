@@ -0,0 +1,344 @@
+package symbols
+
+import (
+	"reflect"
+	"testing"
+)
+
+const frontMatterInput = `---
+title: "My Note: part 1" # the title
+aliases:
+  - First Alias
+  - 'Second'
+tags: [project, "#work/client"]
+created: 2023-01-02
+---
+# Heading
+#[[inline-tag]]`
+
+func TestParseShouldReturnFrontMatterRaw(t *testing.T) {
+	expected := `title: "My Note: part 1" # the title
+aliases:
+  - First Alias
+  - 'Second'
+tags: [project, "#work/client"]
+created: 2023-01-02`
+
+	res, err := Parse(frontMatterInput)
+	if res.FrontMatter == nil {
+		t.Fatalf("Parse(%q) returned no front matter", frontMatterInput)
+	}
+	if res.FrontMatter.Raw != expected {
+		failMessageString(t, frontMatterInput, res.FrontMatter.Raw, err, expected)
+	}
+}
+
+func TestParseShouldReturnFrontMatterLineRange(t *testing.T) {
+	res, err := Parse(frontMatterInput)
+	if res.FrontMatter.LineNo != 0 {
+		failMessageInt(t, frontMatterInput, res.FrontMatter.LineNo, err, 0)
+	}
+	if res.FrontMatter.EndLineNo != 7 {
+		failMessageInt(t, frontMatterInput, res.FrontMatter.EndLineNo, err, 7)
+	}
+}
+
+func TestParseShouldReturnFrontMatterValues(t *testing.T) {
+	expected := map[string]interface{}{
+		"title":   "My Note: part 1",
+		"aliases": []interface{}{"First Alias", "Second"},
+		"tags":    []interface{}{"project", "#work/client"},
+		"created": "2023-01-02",
+	}
+
+	res, _ := Parse(frontMatterInput)
+	if res.FrontMatter.Err != nil {
+		t.Fatalf("Parse(%q) front matter error %v", frontMatterInput, res.FrontMatter.Err)
+	}
+	if !reflect.DeepEqual(res.FrontMatter.Values, expected) {
+		t.Fatalf("Parse(%q) front matter = %#v, expected %#v", frontMatterInput, res.FrontMatter.Values, expected)
+	}
+}
+
+func TestParseShouldMergeFrontMatterTags(t *testing.T) {
+	res, err := Parse(frontMatterInput)
+	if len(res.Tags) != 3 {
+		failMessageInt(t, frontMatterInput, len(res.Tags), err, 3)
+	}
+
+	expected := []string{"project", "work/client", "inline-tag"}
+	for i, want := range expected {
+		if res.Tags[i].Value != want {
+			failMessageString(t, frontMatterInput, res.Tags[i].Value, err, want)
+		}
+	}
+}
+
+func TestParseShouldReturnFrontMatterTagPosition(t *testing.T) {
+	res, err := Parse(frontMatterInput)
+	check := res.Tags[1]
+
+	if check.Lit != "#work/client" {
+		failMessageString(t, frontMatterInput, check.Lit, err, "#work/client")
+	}
+	if check.LineNo != 5 {
+		failMessageInt(t, frontMatterInput, check.LineNo, err, 5)
+	}
+	if check.CharStart != 18 {
+		failMessageInt(t, frontMatterInput, check.CharStart, err, 18)
+	}
+	if check.CharEnd != 30 {
+		failMessageInt(t, frontMatterInput, check.CharEnd, err, 30)
+	}
+}
+
+func TestParseShouldReturnFrontMatterAliases(t *testing.T) {
+	res, err := Parse(frontMatterInput)
+	if len(res.Aliases) != 2 {
+		failMessageInt(t, frontMatterInput, len(res.Aliases), err, 2)
+	}
+
+	check := res.Aliases[0]
+	if check.Type != ALIAS {
+		failMessageType(t, frontMatterInput, check.Type, err, ALIAS)
+	}
+	if check.Value != "First Alias" {
+		failMessageString(t, frontMatterInput, check.Value, err, "First Alias")
+	}
+	if check.LineNo != 3 {
+		failMessageInt(t, frontMatterInput, check.LineNo, err, 3)
+	}
+	if check.CharStart != 5 {
+		failMessageInt(t, frontMatterInput, check.CharStart, err, 5)
+	}
+}
+
+func TestParseShouldSplitFrontMatterTagString(t *testing.T) {
+	input := "---\ntags: one two, three\nalias: Solo, Other One\n---"
+
+	res, err := Parse(input)
+	expected := []string{"one", "two", "three"}
+	if len(res.Tags) != len(expected) {
+		failMessageInt(t, input, len(res.Tags), err, len(expected))
+	}
+	for i, want := range expected {
+		if res.Tags[i].Value != want {
+			failMessageString(t, input, res.Tags[i].Value, err, want)
+		}
+	}
+	if len(res.Aliases) != 2 || res.Aliases[1].Value != "Other One" {
+		failMessageInt(t, input, len(res.Aliases), err, 2)
+	}
+}
+
+func TestParseShouldNotReturnFrontMatterWhenNotAtStart(t *testing.T) {
+	input := "text\n---\ntitle: x\n---"
+
+	res, err := Parse(input)
+	if res.FrontMatter != nil {
+		failMessageString(t, input, res.FrontMatter.Raw, err, "")
+	}
+}
+
+func TestParseShouldNotReturnUnterminatedFrontMatter(t *testing.T) {
+	input := "---\ntitle: x\n# Title"
+
+	res, err := Parse(input)
+	if res.FrontMatter != nil {
+		failMessageString(t, input, res.FrontMatter.Raw, err, "")
+	}
+	if res.Title.Value != "Title" {
+		failMessageString(t, input, res.Title.Value, err, "Title")
+	}
+}
+
+func TestParseShouldAcceptDotsAsFrontMatterEnd(t *testing.T) {
+	input := "---\ntitle: x\n...\n# Title"
+
+	res, err := Parse(input)
+	if res.FrontMatter == nil || res.FrontMatter.Values["title"] != "x" {
+		t.Fatalf("Parse(%q) = %v, %v, expected front matter", input, res.FrontMatter, err)
+	}
+	if res.Title.Value != "Title" {
+		failMessageString(t, input, res.Title.Value, err, "Title")
+	}
+}
+
+func TestParseShouldKeepInvalidFrontMatterRaw(t *testing.T) {
+	input := "---\n\tbad: x\n---\n# Title"
+
+	res, err := Parse(input)
+	if res.FrontMatter == nil || res.FrontMatter.Err == nil {
+		t.Fatalf("Parse(%q) = %v, %v, expected front matter error", input, res.FrontMatter, err)
+	}
+	if res.FrontMatter.Raw != "\tbad: x" {
+		failMessageString(t, input, res.FrontMatter.Raw, err, "\tbad: x")
+	}
+	if res.Title.Value != "Title" {
+		failMessageString(t, input, res.Title.Value, err, "Title")
+	}
+}
+
+func TestParseShouldNotReturnSymbolsOfInvalidFrontMatter(t *testing.T) {
+	tests := map[string]string{
+		"UnclosedFlow": "---\ntags: [a,\naliases: [b]\n---\ntext",
+		"Alias":        "---\ntags: [a]\naliases: *x\n---\ntext",
+		"TOML":         "+++\ntags = [\"a\"]\naliases = \n+++\ntext",
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			res, err := Parse(input)
+			if res.FrontMatter == nil || res.FrontMatter.Err == nil {
+				t.Fatalf("Parse(%q) = %v, %v, expected front matter error", input, res.FrontMatter, err)
+			}
+			if len(res.Tags) != 0 || len(res.Aliases) != 0 {
+				t.Fatalf("Parse(%q) tags = %v, aliases = %v, expected none", input, res.Tags, res.Aliases)
+			}
+		})
+	}
+}
+
+func TestParseShouldNotTreatFrontMatterAsHeadings(t *testing.T) {
+	input := "---\n# comment: x\ntitle: y\n---"
+
+	res, err := Parse(input)
+	if len(res.Headers) != 0 {
+		failMessageInt(t, input, len(res.Headers), err, 0)
+	}
+}
+
+func TestParseShouldReturnFrontMatterFormat(t *testing.T) {
+	tests := map[string]struct {
+		input string
+		want  FrontMatterFormat
+	}{
+		"YAML":      {"---\ntitle: x\n---", YAML},
+		"TOML":      {"+++\ntitle = \"x\"\n+++", TOML},
+		"JSON":      {"{\n  \"title\": \"x\"\n}", JSON},
+		"JSONOneLn": {"{\"title\": \"x\"}\ntext", JSON},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			res, err := Parse(tc.input)
+			if res.FrontMatter == nil {
+				t.Fatalf("Parse(%q) returned no front matter, %v", tc.input, err)
+			}
+			if res.FrontMatter.Format != tc.want {
+				failMessageString(t, tc.input, string(res.FrontMatter.Format), err, string(tc.want))
+			}
+			if res.FrontMatter.Err != nil {
+				t.Fatalf("Parse(%q) front matter error %v", tc.input, res.FrontMatter.Err)
+			}
+			if res.FrontMatter.Values["title"] != "x" {
+				t.Fatalf("Parse(%q) front matter = %#v, expected title x", tc.input, res.FrontMatter.Values)
+			}
+		})
+	}
+}
+
+func TestParseShouldReturnTOMLFrontMatter(t *testing.T) {
+	input := `+++
+title = "Hugo Post"
+tags = ["go", "#web"]
+aliases = ["/old/url"]
+[params]
+weight = 10
++++
+# Heading`
+
+	res, err := Parse(input)
+	fm := res.FrontMatter
+	if fm.EndLineNo != 6 {
+		failMessageInt(t, input, fm.EndLineNo, err, 6)
+	}
+	params, _ := fm.Values["params"].(map[string]interface{})
+	if params["weight"] != 10 {
+		t.Fatalf("Parse(%q) params = %#v, expected weight 10", input, params)
+	}
+	if len(res.Tags) != 2 || res.Tags[1].Value != "web" {
+		failMessageInt(t, input, len(res.Tags), err, 2)
+	}
+	if res.Tags[1].CharStart != 16 {
+		failMessageInt(t, input, res.Tags[1].CharStart, err, 16)
+	}
+	if len(res.Aliases) != 1 || res.Aliases[0].Value != "/old/url" {
+		failMessageInt(t, input, len(res.Aliases), err, 1)
+	}
+	if res.Title.Value != "Heading" {
+		failMessageString(t, input, res.Title.Value, err, "Heading")
+	}
+}
+
+func TestParseShouldReturnJSONFrontMatter(t *testing.T) {
+	input := `{
+  "title": "JSON",
+  "aliases": ["J", "Son"],
+  "tags": "a b"
+}
+# Heading`
+
+	res, err := Parse(input)
+	fm := res.FrontMatter
+	if fm.LineNo != 0 || fm.EndLineNo != 4 {
+		failMessageInt(t, input, fm.EndLineNo, err, 4)
+	}
+	if fm.Raw != input[:len(input)-len("\n# Heading")] {
+		failMessageString(t, input, fm.Raw, err, input[:len(input)-len("\n# Heading")])
+	}
+	if len(res.Aliases) != 2 || res.Aliases[1].Value != "Son" {
+		failMessageInt(t, input, len(res.Aliases), err, 2)
+	}
+	if res.Aliases[1].LineNo != 2 || res.Aliases[1].CharStart != 21 {
+		failMessageInt(t, input, res.Aliases[1].CharStart, err, 21)
+	}
+	if len(res.Tags) != 2 {
+		failMessageInt(t, input, len(res.Tags), err, 2)
+	}
+	if res.Title.Value != "Heading" {
+		failMessageString(t, input, res.Title.Value, err, "Heading")
+	}
+}
+
+func TestParseShouldNotTreatBracesAsJSONFrontMatter(t *testing.T) {
+	input := "{{< shortcode >}}\n# Heading"
+
+	res, err := Parse(input)
+	if res.FrontMatter != nil {
+		failMessageString(t, input, res.FrontMatter.Raw, err, "")
+	}
+}
+
+func TestParseShouldReturnFrontMatterErrorPosition(t *testing.T) {
+	tests := map[string]struct {
+		input  string
+		lineNo int
+		column int
+	}{
+		"YAMLIndent":    {"---\ntitle: x\n  bad: y\n---", 2, 3},
+		"YAMLQuote":     {"---\ntitle: x\nname: \"open\n---", 2, 7},
+		"TOMLValue":     {"+++\ntitle = \n+++", 1, 9},
+		"TOMLDuplicate": {"+++\na = 1\na = 2\n+++", 2, 1},
+		"TOMLString":    {"+++\na = \"open\n+++", 1, 5},
+		"JSONSyntax":    {"{\n  \"a\": 1\n  \"b\": 2\n}", 2, 3},
+		"JSONTrailing":  {"{\"a\": 1} tail\ntext", 0, 10},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			res, _ := Parse(tc.input)
+			if res.FrontMatter == nil {
+				t.Fatalf("Parse(%q) returned no front matter", tc.input)
+			}
+
+			fmErr, ok := res.FrontMatter.Err.(*FrontMatterError)
+			if !ok {
+				t.Fatalf("Parse(%q) front matter error = %v, expected *FrontMatterError", tc.input, res.FrontMatter.Err)
+			}
+			if fmErr.LineNo != tc.lineNo || fmErr.Column != tc.column {
+				t.Fatalf("Parse(%q) front matter error at %d:%d, expected %d:%d (%v)", tc.input, fmErr.LineNo, fmErr.Column, tc.lineNo, tc.column, fmErr)
+			}
+		})
+	}
+}
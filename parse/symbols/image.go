@@ -0,0 +1,101 @@
+package symbols
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/siasmey/markdown/parse/lexer"
+)
+
+func (p *Parser) parseImage(bang lexer.Token) (Symbol, bool) {
+	open := p.next()
+	if open.TokenType != lexer.LEFTBRK {
+		p.backup(false, open)
+		return Symbol{}, false
+	}
+
+	var sym Symbol
+	if second := p.next(); second.TokenType == lexer.LEFTBRK {
+		var ok bool
+		if sym, ok = p.parseWikiLink(bang, open, second); !ok {
+			p.pending = append(p.pending, p.other(open))
+			return Symbol{}, false
+		}
+		sym.Type = EMBED
+		if sym.Alias != nil {
+			if width, height, ok := parseSize(sym.Alias.Value); ok {
+				sym.Width, sym.Height = width, height
+				sym.Alias = nil
+			}
+		}
+	} else {
+		p.backup(false, second)
+		var ok bool
+		if sym, ok = p.parseBracketLink(open); !ok {
+			p.backup(false, open)
+			return Symbol{}, false
+		}
+		sym.Type = IMAGE
+		p.setImageSize(&sym)
+	}
+
+	sym.Lit = bang.Lit + sym.Lit
+	sym.CharStart = p.column(bang)
+	sym.Offset = bang.Offset
+	return sym, true
+}
+
+// Obsidian lets the alt text end in a size hint, as in ![diagram|300](x.png).
+func (p *Parser) setImageSize(sym *Symbol) {
+	alt := sym.Text
+	if alt == nil {
+		return
+	}
+
+	i := strings.LastIndex(alt.Value, "|")
+	if i < 0 {
+		return
+	}
+	width, height, ok := parseSize(alt.Value[i+1:])
+	if !ok {
+		return
+	}
+	sym.Width, sym.Height = width, height
+
+	sym.Text = nil
+	if value := strings.TrimRight(alt.Value[:i], " \t\r\n"); value != "" {
+		end := Position{Line: alt.LineNo, Column: alt.CharStart + lexer.Width(value, p.Encoding)}
+		if j := strings.LastIndexAny(value, "\r\n"); j >= 0 {
+			end.Line += strings.Count(value, "\n") + strings.Count(value, "\r") - strings.Count(value, "\r\n")
+			end.Column = lexer.Width(value[j+1:], p.Encoding) + 1
+		}
+		sym.Text = &Span{
+			Value:     value,
+			LineNo:    alt.LineNo,
+			CharStart: alt.CharStart,
+			CharEnd:   end.Column,
+			Offset:    alt.Offset,
+			EndOffset: alt.Offset + len(value),
+			Range:     Range{Start: alt.Range.Start, End: end},
+		}
+	}
+}
+
+func parseSize(hint string) (int, int, bool) {
+	hint = strings.TrimSpace(hint)
+	w, h, found := strings.Cut(hint, "x")
+
+	width, err := strconv.Atoi(w)
+	if err != nil || width < 0 {
+		return 0, 0, false
+	}
+	if !found {
+		return width, 0, true
+	}
+
+	height, err := strconv.Atoi(h)
+	if err != nil || height < 0 {
+		return 0, 0, false
+	}
+	return width, height, true
+}
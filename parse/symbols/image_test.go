@@ -0,0 +1,147 @@
+package symbols
+
+import "testing"
+
+func TestParseShouldReturnImage(t *testing.T) {
+	tests := map[string]struct {
+		input  string
+		alt    string
+		src    string
+		title  string
+		width  int
+		height int
+	}{
+		"Simple":     {"![diagram](img.png)", "diagram", "img.png", "<nil>", 0, 0},
+		"Title":      {"![diagram](img.png \"Figure 1\")", "diagram", "img.png", "Figure 1", 0, 0},
+		"EmptyAlt":   {"![](img.png)", "<nil>", "img.png", "<nil>", 0, 0},
+		"Width":      {"![diagram|300](img.png)", "diagram", "img.png", "<nil>", 300, 0},
+		"WidthHight": {"![diagram|300x200](img.png)", "diagram", "img.png", "<nil>", 300, 200},
+		"OnlySize":   {"![|64](img.png)", "<nil>", "img.png", "<nil>", 64, 0},
+		"PipeInAlt":  {"![a|b](img.png)", "a|b", "img.png", "<nil>", 0, 0},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			res, err := Parse(tc.input)
+			if len(res.Images) != 1 || len(res.Links) != 0 {
+				failMessageInt(t, tc.input, len(res.Images), err, 1)
+			}
+
+			img := res.Images[0]
+			if img.Type != IMAGE {
+				failMessageString(t, tc.input, string(img.Type), err, string(IMAGE))
+			}
+			if img.Lit != tc.input {
+				failMessageString(t, tc.input, img.Lit, err, tc.input)
+			}
+			if got := spanValue(img.Text); got != tc.alt {
+				failMessageString(t, tc.input, got, err, tc.alt)
+			}
+			if img.Value != tc.src || spanValue(img.Destination) != tc.src {
+				failMessageString(t, tc.input, img.Value, err, tc.src)
+			}
+			if got := spanValue(img.Title); got != tc.title {
+				failMessageString(t, tc.input, got, err, tc.title)
+			}
+			if img.Width != tc.width || img.Height != tc.height {
+				t.Fatalf("Parse(%q) size = %dx%d, expected %dx%d", tc.input, img.Width, img.Height, tc.width, tc.height)
+			}
+		})
+	}
+}
+
+func TestParseShouldReturnEmbed(t *testing.T) {
+	tests := map[string]struct {
+		input   string
+		target  string
+		heading string
+		alias   string
+		width   int
+		height  int
+	}{
+		"Note":       {"![[Other note]]", "Other note", "<nil>", "<nil>", 0, 0},
+		"Heading":    {"![[Other note#Part]]", "Other note", "Part", "<nil>", 0, 0},
+		"Width":      {"![[img.png|300]]", "img.png", "<nil>", "<nil>", 300, 0},
+		"WidthHight": {"![[img.png|300x200]]", "img.png", "<nil>", "<nil>", 300, 200},
+		"Alias":      {"![[Other note|shown]]", "Other note", "<nil>", "shown", 0, 0},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			res, err := Parse(tc.input)
+			if len(res.Embeds) != 1 || len(res.WikiLinks) != 0 {
+				failMessageInt(t, tc.input, len(res.Embeds), err, 1)
+			}
+
+			embed := res.Embeds[0]
+			if embed.Type != EMBED {
+				failMessageString(t, tc.input, string(embed.Type), err, string(EMBED))
+			}
+			if embed.Lit != tc.input {
+				failMessageString(t, tc.input, embed.Lit, err, tc.input)
+			}
+			if got := spanValue(embed.Target); got != tc.target {
+				failMessageString(t, tc.input, got, err, tc.target)
+			}
+			if got := spanValue(embed.Heading); got != tc.heading {
+				failMessageString(t, tc.input, got, err, tc.heading)
+			}
+			if got := spanValue(embed.Alias); got != tc.alias {
+				failMessageString(t, tc.input, got, err, tc.alias)
+			}
+			if embed.Width != tc.width || embed.Height != tc.height {
+				t.Fatalf("Parse(%q) size = %dx%d, expected %dx%d", tc.input, embed.Width, embed.Height, tc.width, tc.height)
+			}
+		})
+	}
+}
+
+func TestParseShouldReturnImagePosition(t *testing.T) {
+	input := "x\nsee ![alt](a.png) and ![[b.png|10]]"
+
+	res, err := Parse(input)
+	img := res.Images[0]
+	if img.LineNo != 1 || img.CharStart != 5 || img.CharEnd != 18 {
+		failMessageInt(t, input, img.CharStart, err, 5)
+	}
+	if img.Text.CharStart != 7 || img.Text.CharEnd != 10 {
+		failMessageInt(t, input, img.Text.CharStart, err, 7)
+	}
+
+	embed := res.Embeds[0]
+	if embed.LineNo != 1 || embed.CharStart != 23 || embed.CharEnd != 36 {
+		failMessageInt(t, input, embed.CharStart, err, 23)
+	}
+	if embed.Target.CharStart != 26 || embed.Target.CharEnd != 31 {
+		failMessageInt(t, input, embed.Target.CharStart, err, 26)
+	}
+}
+
+func TestParseBangShouldStayText(t *testing.T) {
+	input := "Wow! [link](u) !#tag !"
+
+	res, err := Parse(input)
+	if len(res.Images) != 0 || len(res.Embeds) != 0 {
+		failMessageInt(t, input, len(res.Images), err, 0)
+	}
+	if len(res.Links) != 1 {
+		failMessageInt(t, input, len(res.Links), err, 1)
+	}
+	if len(res.Tags) != 1 {
+		failMessageInt(t, input, len(res.Tags), err, 1)
+	}
+}
+
+func TestOutlineShouldAttachImagesAndEmbeds(t *testing.T) {
+	input := "# One\n![a](a.png)\n## Two\n![[b]]"
+
+	res, _ := Parse(input)
+	root := res.Outline()
+	one := root.Children[0]
+	if len(one.Images) != 1 {
+		t.Fatalf("Outline(%q) section One images = %d, expected 1", input, len(one.Images))
+	}
+	if len(one.Children[0].Embeds) != 1 {
+		t.Fatalf("Outline(%q) section Two embeds = %d, expected 1", input, len(one.Children[0].Embeds))
+	}
+}
@@ -0,0 +1,449 @@
+package symbols
+
+import (
+	"strings"
+
+	"github.com/siasmey/markdown/parse/lexer"
+)
+
+func (p *Parser) parseLink(start lexer.Token) (Symbol, error) {
+	after := p.next()
+	if after.TokenType == lexer.LEFTBRK {
+		if sym, ok := p.parseWikiLink(start, start, after); ok {
+			return sym, nil
+		}
+		return p.other(start), nil
+	}
+	p.backup(false, after)
+
+	if p.linkText > 0 {
+		return p.other(start), nil
+	}
+	if sym, ok := p.parseBracketLink(start); ok {
+		return sym, nil
+	}
+	return p.other(start), nil
+}
+
+// parseBracketLink reads an inline link [text](destination "title") or one of
+// the reference forms [text][label], [label][] and [label]. Anything that
+// does not complete one of those shapes is handed back so the brackets stay
+// plain text. The text of a link is handed back too, to be parsed for the
+// symbols it holds, unless the link is itself inside the text of one: a scan
+// for every level of images nested in images would take quadratic time.
+func (p *Parser) parseBracketLink(start lexer.Token) (Symbol, bool) {
+	tokens := []lexer.Token{start}
+
+	text, ok := p.scanLinkText(&tokens)
+	if !ok {
+		p.backup(false, tokens[1:]...)
+		return Symbol{}, false
+	}
+
+	sym := Symbol{
+		Type:      LINK,
+		LineNo:    start.LineNr,
+		CharStart: p.column(start),
+		Offset:    start.Offset,
+		Text:      p.newSpan(text),
+	}
+	sym.Kind, sym.Label, ok = p.scanLinkTail(&sym, &tokens)
+	if !ok {
+		p.backup(false, tokens[1:]...)
+		return Symbol{}, false
+	}
+
+	closing := tokens[len(tokens)-1]
+	sym.Lit = literal(tokens)
+	sym.EndLineNo = closing.LineNr
+	sym.CharEnd = p.endColumn(closing)
+	sym.EndOffset = closing.EndOffset
+	if sym.Destination != nil {
+		sym.Value = unescape(sym.Destination.Value)
+	}
+	if p.linkText == 0 {
+		p.backup(false, append(append([]lexer.Token{}, text...), textEnd)...)
+		p.linkText++
+	}
+	return sym, true
+}
+
+func (p *Parser) scanLinkTail(sym *Symbol, tokens *[]lexer.Token) (LinkKind, *Span, bool) {
+	mark := len(*tokens)
+
+	switch tk := p.next(); tk.TokenType {
+	case lexer.LEFTPRN:
+		*tokens = append(*tokens, tk)
+		if destination, title, ok := p.scanLinkTarget(tokens); ok {
+			sym.Destination = p.newSpan(destination)
+			sym.Title = p.newSpan(title)
+			return INLINE, nil, true
+		}
+	case lexer.LEFTBRK:
+		*tokens = append(*tokens, tk)
+		label, ok := p.scanLabel(tokens)
+		if ok && len(trimSpace(label)) == 0 {
+			return COLLAPSED, sym.Text, sym.Text != nil
+		}
+		if ok {
+			return FULL, p.newSpan(label), true
+		}
+	default:
+		*tokens = append(*tokens, tk)
+	}
+
+	p.backup(false, (*tokens)[mark:]...)
+	*tokens = (*tokens)[:mark]
+	return SHORTCUT, sym.Text, sym.Text != nil
+}
+
+// scanLabel reads a reference label up to its closing bracket. Labels cannot
+// contain unescaped brackets or leave the paragraph.
+func (p *Parser) scanLabel(tokens *[]lexer.Token) ([]lexer.Token, bool) {
+	label := []lexer.Token{}
+
+	for {
+		tk := p.next()
+		*tokens = append(*tokens, tk)
+
+		switch {
+		case tk.TokenType == lexer.EOF || tk.TokenType == lexer.LEFTBRK:
+			return nil, false
+		case tk.TokenType == lexer.RIGHTBRK:
+			return label, true
+		case tk.TokenType == lexer.NL && p.endsParagraph(tokens):
+			return nil, false
+		case tk.Lit == "\\":
+			escaped := p.next()
+			*tokens = append(*tokens, escaped)
+			label = append(label, tk, escaped)
+			continue
+		}
+		label = append(label, tk)
+	}
+}
+
+// parseDefinition reads a link reference definition [label]: destination
+// "title" from the start of a line. The title may follow on the next line;
+// without one the definition ends with the destination's line.
+func (p *Parser) parseDefinition(line []lexer.Token) (Symbol, bool) {
+	open := line[len(line)-1]
+	tokens := append([]lexer.Token{}, line...)
+
+	label, ok := p.scanLabel(&tokens)
+	if ok {
+		colon := p.next()
+		tokens = append(tokens, colon)
+		ok = colon.TokenType == lexer.ILLEGAL && colon.Lit == ":" && len(trimSpace(label)) > 0
+	}
+
+	var destination, title []lexer.Token
+	if ok {
+		var tk lexer.Token
+		destination, tk, ok = p.scanDestination(p.next(), &tokens)
+		if ok && (len(destination) > 0 || tokens[len(tokens)-1].Lit == ">") {
+			title, ok = p.scanDefinitionTitle(tk, &tokens)
+		} else {
+			tokens = append(tokens, tk)
+			ok = false
+		}
+	}
+	if !ok {
+		p.backup(false, tokens[len(line):]...)
+		return Symbol{}, false
+	}
+
+	lineEnd, charEnd, endOffset := p.endOf(tokens)
+	sym := Symbol{
+		Type:        DEFINITION,
+		Lit:         trimNewLine(literal(tokens[len(line)-1:])),
+		LineNo:      open.LineNr,
+		EndLineNo:   lineEnd,
+		CharStart:   p.column(open),
+		CharEnd:     charEnd,
+		Offset:      open.Offset,
+		EndOffset:   endOffset,
+		Label:       p.newSpan(label),
+		Destination: p.newSpan(destination),
+		Title:       p.newSpan(title),
+	}
+	if sym.Destination != nil {
+		sym.Value = unescape(sym.Destination.Value)
+	}
+	return sym, true
+}
+
+func (p *Parser) scanDefinitionTitle(tk lexer.Token, tokens *[]lexer.Token) ([]lexer.Token, bool) {
+	mark := len(*tokens)
+	separated := tk.TokenType == lexer.WS || tk.TokenType == lexer.NL
+	tk = p.skipLinkSpace(tk, tokens)
+
+	if closer, ok := titleCloser(tk); ok && separated {
+		if title, closed := p.scanLinkTitle(tk, closer, tokens); closed {
+			if p.scanLineEnd(tokens) {
+				return title, true
+			}
+		}
+		tk = p.next()
+	}
+
+	p.backup(false, append((*tokens)[mark:], tk)...)
+	*tokens = (*tokens)[:mark]
+	return []lexer.Token{}, p.scanLineEnd(tokens)
+}
+
+// scanLineEnd accepts trailing white space up to and including the new line.
+func (p *Parser) scanLineEnd(tokens *[]lexer.Token) bool {
+	tk := p.next()
+	if tk.TokenType == lexer.WS {
+		*tokens = append(*tokens, tk)
+		tk = p.next()
+	}
+
+	switch tk.TokenType {
+	case lexer.NL:
+		*tokens = append(*tokens, tk)
+		return true
+	case lexer.EOF:
+		return true
+	}
+	p.backup(false, tk)
+	return false
+}
+
+// resolveReferences looks up the definition of every reference link. Full and
+// collapsed references without one are kept and flagged, a shortcut without
+// one is only text in brackets and is dropped.
+func resolveReferences(links []Symbol, definitions map[string]Symbol) []Symbol {
+	resolved := []Symbol{}
+
+	for _, sym := range links {
+		if sym.Kind.IsReference() {
+			def, ok := definitions[normalizeLabel(sym.Label.Value)]
+			switch {
+			case ok:
+				sym.Definition = &def
+				sym.Destination = def.Destination
+				sym.Title = def.Title
+				sym.Value = def.Value
+			case sym.Kind == SHORTCUT:
+				continue
+			default:
+				sym.Unresolved = true
+			}
+		}
+		resolved = append(resolved, sym)
+	}
+	return resolved
+}
+
+func normalizeLabel(label string) string {
+	return strings.ToLower(strings.Join(strings.Fields(label), " "))
+}
+
+func (p *Parser) scanLinkText(tokens *[]lexer.Token) ([]lexer.Token, bool) {
+	text := []lexer.Token{}
+	depth := 0
+
+	if p.unclosedText.holds((*tokens)[len(*tokens)-1]) {
+		return nil, false
+	}
+
+	for {
+		tk := p.next()
+		if tk.TokenType == lexer.EOF {
+			p.markUnclosedText(*tokens)
+			return nil, false
+		}
+		*tokens = append(*tokens, tk)
+
+		if escaped, ok := p.scanEscape(tk); ok {
+			*tokens = append(*tokens, escaped)
+			text = append(text, tk, escaped)
+			continue
+		}
+
+		switch tk.TokenType {
+		case lexer.LEFTBRK:
+			depth++
+		case lexer.RIGHTBRK:
+			if depth == 0 {
+				return text, true
+			}
+			depth--
+		case lexer.TICK:
+			if span, ok := p.scanCodeSpan(tk); ok {
+				*tokens = append(*tokens, span...)
+				text = append(text, tk)
+				text = append(text, span...)
+				continue
+			}
+		case lexer.NL:
+			before := len(*tokens)
+			if p.endsParagraph(tokens) {
+				p.markUnclosedText(*tokens)
+				return nil, false
+			}
+			text = append(text, tk)
+			text = append(text, (*tokens)[before:]...)
+			continue
+		}
+		text = append(text, tk)
+	}
+}
+
+func (p *Parser) scanLinkTarget(tokens *[]lexer.Token) ([]lexer.Token, []lexer.Token, bool) {
+	open := (*tokens)[len(*tokens)-1]
+	if p.unclosedTarget.holds(open) {
+		return nil, nil, false
+	}
+	mark := len(*tokens)
+
+	destination, tk, ok := p.scanDestination(p.next(), tokens)
+	if !ok {
+		*tokens = append(*tokens, tk)
+		return nil, nil, false
+	}
+
+	separated := tk.TokenType == lexer.WS || tk.TokenType == lexer.NL
+	tk = p.skipLinkSpace(tk, tokens)
+
+	title := []lexer.Token{}
+	if closer, ok := titleCloser(tk); ok && (separated || len(destination) == 0) {
+		var closed bool
+		if title, closed = p.scanLinkTitle(tk, closer, tokens); !closed {
+			p.markUnclosedTarget(open, destination, (*tokens)[mark:])
+			p.diagnoseUnclosedTarget(open, (*tokens)[mark:])
+			return nil, nil, false
+		}
+		tk = p.skipLinkSpace(p.next(), tokens)
+	}
+
+	*tokens = append(*tokens, tk)
+	if tk.TokenType != lexer.RIGHTPRN {
+		p.markUnclosedTarget(open, destination, (*tokens)[mark:])
+		p.diagnoseUnclosedTarget(open, (*tokens)[mark:])
+		return nil, nil, false
+	}
+	return destination, title, true
+}
+
+// diagnoseUnclosedTarget reports the opening parenthesis of a failed link
+// target scan if no closing parenthesis follows it before the paragraph ends.
+// The tokens read past the scan are handed back.
+func (p *Parser) diagnoseUnclosedTarget(open lexer.Token, scanned []lexer.Token) {
+	if contains(scanned, lexer.RIGHTPRN) {
+		return
+	}
+
+	ahead := []lexer.Token{}
+	end := scanned[len(scanned)-1]
+	for end.TokenType != lexer.NL && end.TokenType != lexer.EOF && !p.unclosedParen.holds(open) {
+		tk := p.next()
+		ahead = append(ahead, tk)
+		switch {
+		case tk.TokenType == lexer.RIGHTPRN:
+			p.backup(false, ahead...)
+			return
+		case tk.TokenType == lexer.EOF || tk.TokenType == lexer.NL && p.endsParagraph(&ahead):
+			end = tk
+		}
+	}
+	p.backup(false, ahead...)
+	p.unclosedParen = widen(p.unclosedParen, open, end)
+	p.diagnose(WARNING, CodeUnterminatedLink, p.tokenRange(open, open), "unterminated link, missing )")
+}
+
+// scanDestination returns the destination tokens and the first token after
+// them, which is not yet added to tokens.
+func (p *Parser) scanDestination(tk lexer.Token, tokens *[]lexer.Token) ([]lexer.Token, lexer.Token, bool) {
+	destination := []lexer.Token{}
+	tk = p.skipLinkSpace(tk, tokens)
+
+	if tk.TokenType == lexer.ILLEGAL && tk.Lit == "<" {
+		for {
+			*tokens = append(*tokens, tk)
+			if tk = p.next(); tk.TokenType == lexer.NL || tk.TokenType == lexer.EOF {
+				return nil, tk, false
+			}
+			if tk.Lit == ">" {
+				*tokens = append(*tokens, tk)
+				return destination, p.next(), true
+			}
+			if escaped, ok := p.scanEscape(tk); ok {
+				*tokens = append(*tokens, tk)
+				destination = append(destination, tk)
+				tk = escaped
+			}
+			destination = append(destination, tk)
+		}
+	}
+
+	depth := 0
+	for tk.TokenType != lexer.WS && tk.TokenType != lexer.NL && tk.TokenType != lexer.EOF {
+		if escaped, ok := p.scanEscape(tk); ok {
+			*tokens = append(*tokens, tk, escaped)
+			destination = append(destination, tk, escaped)
+			tk = p.next()
+			continue
+		}
+		if tk.TokenType == lexer.LEFTPRN {
+			depth++
+		} else if tk.TokenType == lexer.RIGHTPRN {
+			if depth == 0 {
+				break
+			}
+			depth--
+		}
+		*tokens = append(*tokens, tk)
+		destination = append(destination, tk)
+		tk = p.next()
+	}
+	return destination, tk, true
+}
+
+func (p *Parser) skipLinkSpace(tk lexer.Token, tokens *[]lexer.Token) lexer.Token {
+	for tk.TokenType == lexer.WS || tk.TokenType == lexer.NL {
+		*tokens = append(*tokens, tk)
+		if tk.TokenType == lexer.NL && p.endsParagraph(tokens) {
+			return p.next()
+		}
+		tk = p.next()
+	}
+	return tk
+}
+
+func titleCloser(tk lexer.Token) (string, bool) {
+	switch {
+	case tk.TokenType == lexer.LEFTPRN:
+		return ")", true
+	case tk.TokenType == lexer.ILLEGAL && (tk.Lit == "\"" || tk.Lit == "'"):
+		return tk.Lit, true
+	}
+	return "", false
+}
+
+func (p *Parser) scanLinkTitle(open lexer.Token, closer string, tokens *[]lexer.Token) ([]lexer.Token, bool) {
+	*tokens = append(*tokens, open)
+	title := []lexer.Token{}
+
+	for {
+		tk := p.next()
+		*tokens = append(*tokens, tk)
+
+		switch {
+		case tk.TokenType == lexer.EOF:
+			return nil, false
+		case tk.Lit == closer:
+			return title, true
+		case tk.TokenType == lexer.NL && p.endsParagraph(tokens):
+			return nil, false
+		case tk.Lit == "\\":
+			escaped := p.next()
+			*tokens = append(*tokens, escaped)
+			title = append(title, escaped)
+			continue
+		}
+		title = append(title, tk)
+	}
+}
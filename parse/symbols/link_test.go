@@ -0,0 +1,152 @@
+package symbols
+
+import "testing"
+
+func TestParseShouldReturnLinkFields(t *testing.T) {
+	tests := map[string]struct {
+		input       string
+		text        string
+		destination string
+		title       string
+	}{
+		"Plain":           {"[text](http://x)", "text", "http://x", "<nil>"},
+		"DoubleQuoted":    {"[text](http://x \"title\")", "text", "http://x", "title"},
+		"SingleQuoted":    {"[text](http://x 'a title')", "text", "http://x", "a title"},
+		"ParenTitle":      {"[text](http://x (title))", "text", "http://x", "title"},
+		"EscapedQuote":    {"[t](u \"say \\\"hi\\\"\")", "t", "u", "say \"hi\""},
+		"AngleBrackets":   {"[text](<my file.md> \"t\")", "text", "my file.md", "t"},
+		"BalancedParens":  {"[wiki](https://en.wikipedia.org/wiki/Go_(language))", "wiki", "https://en.wikipedia.org/wiki/Go_(language)", "<nil>"},
+		"Fragment":        {"[sec](page.md#section)", "sec", "page.md#section", "<nil>"},
+		"EmptyDest":       {"[text]()", "text", "<nil>", "<nil>"},
+		"NestedBrackets":  {"[a [b] c](u)", "a [b] c", "u", "<nil>"},
+		"CodeSpanInText":  {"[`a]`](u)", "`a]`", "u", "<nil>"},
+		"PaddedTarget":    {"[text](  u  \"t\"  )", "text", "u", "t"},
+		"TitleOnNextLine": {"[text](u\n\"t\")", "text", "u", "t"},
+		"Unicode":         {"[café](ü \"日本\")", "café", "ü", "日本"},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			res, err := Parse(tc.input)
+			if len(res.Links) != 1 {
+				failMessageInt(t, tc.input, len(res.Links), err, 1)
+			}
+
+			link := res.Links[0]
+			if link.Lit != tc.input {
+				failMessageString(t, tc.input, link.Lit, err, tc.input)
+			}
+			if got := spanValue(link.Text); got != tc.text {
+				failMessageString(t, tc.input, got, err, tc.text)
+			}
+			if got := spanValue(link.Destination); got != tc.destination {
+				failMessageString(t, tc.input, got, err, tc.destination)
+			}
+			if got := spanValue(link.Title); got != tc.title {
+				failMessageString(t, tc.input, got, err, tc.title)
+			}
+		})
+	}
+}
+
+func TestParseShouldReturnLinkFieldRanges(t *testing.T) {
+	input := "x\nsee [text](http://x \"title\") end"
+
+	res, err := Parse(input)
+	link := res.Links[0]
+	tests := map[string]struct {
+		span  *Span
+		start int
+		end   int
+	}{
+		"Text":        {link.Text, 6, 10},
+		"Destination": {link.Destination, 12, 20},
+		"Title":       {link.Title, 22, 27},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if tc.span.LineNo != 1 {
+				failMessageInt(t, input, tc.span.LineNo, err, 1)
+			}
+			if tc.span.CharStart != tc.start {
+				failMessageInt(t, input, tc.span.CharStart, err, tc.start)
+			}
+			if tc.span.CharEnd != tc.end {
+				failMessageInt(t, input, tc.span.CharEnd, err, tc.end)
+			}
+		})
+	}
+	if link.Value != "http://x" {
+		failMessageString(t, input, link.Value, err, "http://x")
+	}
+	if link.CharStart != 5 || link.CharEnd != 29 {
+		failMessageInt(t, input, link.CharEnd, err, 29)
+	}
+}
+
+func TestParseShouldNotReturnLink(t *testing.T) {
+	tests := map[string]string{
+		"NoDestination": "[text] and more",
+		"SpaceBefore":   "[text] (u)",
+		"Unclosed":      "[text](u",
+		"UnclosedTitle": "[text](u \"title)",
+		"AngleNewLine":  "[text](<u\n>)",
+		"BlankLine":     "[text\n\nmore](u)",
+		"JunkAfter":     "[text](u \"t\" x)",
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			res, err := Parse(input)
+			if len(res.Links) != 0 {
+				failMessageString(t, input, res.Links[0].Lit, err, "")
+			}
+		})
+	}
+}
+
+func TestParseShouldContinueAfterBrokenLink(t *testing.T) {
+	input := "[not a link] #tag [[page]] [x](y)"
+
+	res, err := Parse(input)
+	if len(res.Tags) != 1 {
+		failMessageInt(t, input, len(res.Tags), err, 1)
+	}
+	if len(res.WikiLinks) != 1 {
+		failMessageInt(t, input, len(res.WikiLinks), err, 1)
+	}
+	if len(res.Links) != 1 || res.Links[0].Value != "y" {
+		failMessageInt(t, input, len(res.Links), err, 1)
+	}
+}
+
+func TestParseShouldReturnSymbolsInsideLinkText(t *testing.T) {
+	input := "[`c` ![i](j) [[w]] #t](u) after #x"
+
+	res, err := Parse(input)
+	if len(res.Links) != 1 || res.Links[0].Value != "u" {
+		failMessageInt(t, input, len(res.Links), err, 1)
+	}
+	if len(res.CodeSpans) != 1 || res.CodeSpans[0].Value != "c" {
+		failMessageInt(t, input, len(res.CodeSpans), err, 1)
+	}
+	if len(res.Images) != 1 || res.Images[0].Value != "j" {
+		failMessageInt(t, input, len(res.Images), err, 1)
+	}
+	if len(res.WikiLinks) != 1 || res.WikiLinks[0].Value != "w" {
+		failMessageInt(t, input, len(res.WikiLinks), err, 1)
+	}
+	if len(res.Tags) != 2 {
+		failMessageInt(t, input, len(res.Tags), err, 2)
+	}
+}
+
+func TestParseShouldNotNestLinksInLinkText(t *testing.T) {
+	input := "[a [b](c) <http://x> http://y](u)"
+
+	res, err := Parse(input)
+	if len(res.Links) != 1 || res.Links[0].Value != "u" {
+		failMessageInt(t, input, len(res.Links), err, 1)
+	}
+}
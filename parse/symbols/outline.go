@@ -0,0 +1,86 @@
+package symbols
+
+type Section struct {
+	Heading   Symbol
+	Level     int
+	StartLine int
+	EndLine   int
+	Children  []*Section
+	WikiLinks []Symbol
+	Links     []Symbol
+	Images    []Symbol
+	Embeds    []Symbol
+	Tags      []Symbol
+}
+
+// Outline nests the headings of the document into sections. The returned root
+// section spans the whole document and has no heading; links, images, embeds
+// and tags are attached to the innermost section whose line range contains
+// them.
+func (s Symbols) Outline() *Section {
+	lastLine := s.LineCount - 1
+	if lastLine < 0 {
+		lastLine = 0
+	}
+
+	root := &Section{StartLine: 0, EndLine: lastLine}
+	stack := []*Section{root}
+
+	for _, h := range s.Headers {
+		for len(stack) > 1 && stack[len(stack)-1].Level >= h.Level {
+			stack[len(stack)-1].EndLine = h.LineNo - 1
+			stack = stack[:len(stack)-1]
+		}
+
+		section := &Section{
+			Heading:   h,
+			Level:     h.Level,
+			StartLine: h.LineNo,
+			EndLine:   lastLine,
+		}
+		parent := stack[len(stack)-1]
+		parent.Children = append(parent.Children, section)
+		stack = append(stack, section)
+	}
+
+	for _, sym := range s.WikiLinks {
+		if section := root.At(sym.LineNo); section != nil {
+			section.WikiLinks = append(section.WikiLinks, sym)
+		}
+	}
+	for _, sym := range s.Links {
+		if section := root.At(sym.LineNo); section != nil {
+			section.Links = append(section.Links, sym)
+		}
+	}
+	for _, sym := range s.Images {
+		if section := root.At(sym.LineNo); section != nil {
+			section.Images = append(section.Images, sym)
+		}
+	}
+	for _, sym := range s.Embeds {
+		if section := root.At(sym.LineNo); section != nil {
+			section.Embeds = append(section.Embeds, sym)
+		}
+	}
+	for _, sym := range s.Tags {
+		if section := root.At(sym.LineNo); section != nil {
+			section.Tags = append(section.Tags, sym)
+		}
+	}
+
+	return root
+}
+
+func (s *Section) At(line int) *Section {
+	if line < s.StartLine || line > s.EndLine {
+		return nil
+	}
+
+	for _, child := range s.Children {
+		if found := child.At(line); found != nil {
+			return found
+		}
+	}
+	return s
+}
@@ -0,0 +1,144 @@
+package symbols
+
+import "testing"
+
+const outlineInput = `intro [[preamble]]
+# Title
+[[top]]
+## First
+#[[first-tag]]
+### Nested
+[nested](http://nested.com)
+## Second
+[[second]]
+# Appendix
+`
+
+func TestOutlineShouldNestHeadings(t *testing.T) {
+	res, err := Parse(outlineInput)
+	root := res.Outline()
+
+	if len(root.Children) != 2 {
+		failMessageInt(t, outlineInput, len(root.Children), err, 2)
+	}
+	title := root.Children[0]
+	if title.Heading.Value != "Title" {
+		failMessageString(t, outlineInput, title.Heading.Value, err, "Title")
+	}
+	if len(title.Children) != 2 {
+		failMessageInt(t, outlineInput, len(title.Children), err, 2)
+	}
+	if len(title.Children[0].Children) != 1 {
+		failMessageInt(t, outlineInput, len(title.Children[0].Children), err, 1)
+	}
+	if title.Children[0].Children[0].Heading.Value != "Nested" {
+		failMessageString(t, outlineInput, title.Children[0].Children[0].Heading.Value, err, "Nested")
+	}
+	if root.Children[1].Heading.Value != "Appendix" {
+		failMessageString(t, outlineInput, root.Children[1].Heading.Value, err, "Appendix")
+	}
+}
+
+func TestOutlineShouldReturnSectionLineRanges(t *testing.T) {
+	tests := map[string]struct {
+		section func(*Section) *Section
+		start   int
+		end     int
+	}{
+		"Root":     {func(s *Section) *Section { return s }, 0, 10},
+		"Title":    {func(s *Section) *Section { return s.Children[0] }, 1, 8},
+		"First":    {func(s *Section) *Section { return s.Children[0].Children[0] }, 3, 6},
+		"Nested":   {func(s *Section) *Section { return s.Children[0].Children[0].Children[0] }, 5, 6},
+		"Second":   {func(s *Section) *Section { return s.Children[0].Children[1] }, 7, 8},
+		"Appendix": {func(s *Section) *Section { return s.Children[1] }, 9, 10},
+	}
+
+	res, err := Parse(outlineInput)
+	root := res.Outline()
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			check := tc.section(root)
+			if check.StartLine != tc.start {
+				failMessageInt(t, outlineInput, check.StartLine, err, tc.start)
+			}
+			if check.EndLine != tc.end {
+				failMessageInt(t, outlineInput, check.EndLine, err, tc.end)
+			}
+		})
+	}
+}
+
+func TestOutlineShouldAttachSymbolsToInnermostSection(t *testing.T) {
+	res, err := Parse(outlineInput)
+	root := res.Outline()
+
+	if len(root.WikiLinks) != 1 || root.WikiLinks[0].Value != "preamble" {
+		failMessageInt(t, outlineInput, len(root.WikiLinks), err, 1)
+	}
+	title := root.Children[0]
+	if len(title.WikiLinks) != 1 || title.WikiLinks[0].Value != "top" {
+		failMessageInt(t, outlineInput, len(title.WikiLinks), err, 1)
+	}
+	first := title.Children[0]
+	if len(first.Tags) != 1 || first.Tags[0].Value != "first-tag" {
+		failMessageInt(t, outlineInput, len(first.Tags), err, 1)
+	}
+	nested := first.Children[0]
+	if len(nested.Links) != 1 || nested.Links[0].Value != "http://nested.com" {
+		failMessageInt(t, outlineInput, len(nested.Links), err, 1)
+	}
+	second := title.Children[1]
+	if len(second.WikiLinks) != 1 || second.WikiLinks[0].Value != "second" {
+		failMessageInt(t, outlineInput, len(second.WikiLinks), err, 1)
+	}
+}
+
+func TestOutlineShouldHandleSkippedLevels(t *testing.T) {
+	input := `# Title
+### Deep
+## Shallow`
+
+	res, err := Parse(input)
+	title := res.Outline().Children[0]
+
+	if len(title.Children) != 2 {
+		failMessageInt(t, input, len(title.Children), err, 2)
+	}
+	if title.Children[0].EndLine != 1 {
+		failMessageInt(t, input, title.Children[0].EndLine, err, 1)
+	}
+}
+
+func TestOutlineShouldReturnEmptyRootWithoutHeadings(t *testing.T) {
+	input := "just text"
+
+	res, err := Parse(input)
+	root := res.Outline()
+
+	if len(root.Children) != 0 {
+		failMessageInt(t, input, len(root.Children), err, 0)
+	}
+	if root.EndLine != 0 {
+		failMessageInt(t, input, root.EndLine, err, 0)
+	}
+}
+
+func TestSectionAtShouldReturnInnermostSection(t *testing.T) {
+	res, err := Parse(outlineInput)
+	root := res.Outline()
+
+	tests := map[int]string{0: "", 2: "Title", 4: "First", 6: "Nested", 8: "Second", 9: "Appendix"}
+	for line, want := range tests {
+		got := root.At(line)
+		if got == nil {
+			t.Fatalf("expected a section at line %d", line)
+		}
+		if got.Heading.Value != want {
+			failMessageString(t, outlineInput, got.Heading.Value, err, want)
+		}
+	}
+	if root.At(42) != nil {
+		t.Fatalf("expected no section past the end of the document")
+	}
+}
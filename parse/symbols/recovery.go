@@ -0,0 +1,95 @@
+package symbols
+
+import "github.com/siasmey/markdown/parse/lexer"
+
+// Scans for closing brackets give up at the end of their line or paragraph.
+// A line or paragraph full of openers would then be rescanned once for every
+// opener, so failed scans leave behind what they learned and later scans
+// starting inside the same stretch fail fast instead.
+
+// gap is a stretch of tokens a failed scan looked through without finding a
+// closing bracket.
+type gap struct {
+	from lexer.Token
+	to   lexer.Token
+}
+
+// mayClose reports whether the opening bracket from, which needs need more
+// closing brackets than openers up to the end of its line, can still be
+// closed there.
+func (p *Parser) mayClose(from lexer.Token, need int) bool {
+	drop, ok := p.drops[from.Offset]
+	return !ok || drop >= need
+}
+
+// markUnclosed records, for every opening bracket of a failed scan that ran to
+// the end of its line, how many more closing than opening brackets follow it
+// at most before the line ends.
+func (p *Parser) markUnclosed(tokens []lexer.Token) {
+	if p.drops == nil {
+		p.drops = map[int]int{}
+	}
+
+	depth, lowest := 0, 0
+	for i := len(tokens) - 1; i >= 0; i-- {
+		switch tokens[i].TokenType {
+		case lexer.LEFTBRK:
+			p.drops[tokens[i].Offset] = depth - lowest
+			if depth--; depth < lowest {
+				lowest = depth
+			}
+		case lexer.RIGHTBRK:
+			depth++
+		}
+	}
+}
+
+func (g *gap) holds(tk lexer.Token) bool {
+	return g != nil && before(g.from, tk) && before(tk, g.to)
+}
+
+// markUnclosedText records the tokens of a failed link text scan, which ran to
+// the end of its paragraph, if none of them is a closing bracket.
+func (p *Parser) markUnclosedText(tokens []lexer.Token) {
+	if !contains(tokens, lexer.RIGHTBRK) {
+		p.unclosedText = widen(p.unclosedText, tokens[0], tokens[len(tokens)-1])
+	}
+}
+
+// markUnclosedTarget records the raw destination of a failed link target scan
+// if none of the tokens it read is a closing parenthesis. Every link target
+// opened inside that destination ends with it and fails the same way.
+func (p *Parser) markUnclosedTarget(open lexer.Token, destination []lexer.Token, tokens []lexer.Token) {
+	if len(destination) == 0 || contains(tokens, lexer.RIGHTPRN) {
+		return
+	}
+	for _, tk := range tokens {
+		if tk == destination[0] {
+			break
+		}
+		if tk.Lit == "<" {
+			return
+		}
+	}
+	p.unclosedTarget = widen(p.unclosedTarget, open, destination[len(destination)-1])
+}
+
+func widen(g *gap, from, to lexer.Token) *gap {
+	if g != nil && !before(g.to, to) {
+		return g
+	}
+	return &gap{from: from, to: to}
+}
+
+func contains(tokens []lexer.Token, tokenType lexer.TokenType) bool {
+	for _, tk := range tokens {
+		if tk.TokenType == tokenType {
+			return true
+		}
+	}
+	return false
+}
+
+func before(a, b lexer.Token) bool {
+	return a.Offset < b.Offset
+}
@@ -0,0 +1,147 @@
+package symbols
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const tail = "\n\n# Tail\n[[tail-link]] #tail-tag [tail](url)\n"
+
+func TestParseShouldRecoverFromPathologicalInput(t *testing.T) {
+	tests := map[string]string{
+		"StrayBracket":         "line 1\nline 2\na [ stray\n",
+		"StrayBrackets":        strings.Repeat("[", 5000),
+		"OpenWikiLinks":        strings.Repeat("[[", 5000),
+		"OpenWikiLinkLines":    strings.Repeat("[[a\n", 2000),
+		"OpenEmbeds":           strings.Repeat("![[", 3000),
+		"OpenTags":             strings.Repeat("#[[", 3000),
+		"OpenTagLines":         strings.Repeat("#[[a b\n", 2000),
+		"OpenDestinations":     strings.Repeat("[a](", 3000),
+		"OpenTitles":           strings.Repeat("[a](b \"", 2000),
+		"OpenLabels":           strings.Repeat("[a][", 3000),
+		"OpenAutolinks":        strings.Repeat("<https://", 3000),
+		"NestedBrackets":       strings.Repeat("[", 2000) + strings.Repeat("]", 2000),
+		"NestedWikiLinks":      strings.Repeat("[[", 1000) + strings.Repeat("]]", 1000),
+		"ClosingBrackets":      strings.Repeat("]]", 5000),
+		"ClosingParens":        strings.Repeat(")", 5000),
+		"Backticks":            strings.Repeat("` ", 5000),
+		"BacktickRuns":         "x" + strings.Repeat("`", 5000),
+		"Hashes":               strings.Repeat("#", 5000),
+		"HashWords":            strings.Repeat("#a ", 5000),
+		"BareURLs":             strings.Repeat("https://", 3000),
+		"Definitions":          strings.Repeat("[a]: ", 3000),
+		"MixedOpeners":         strings.Repeat("[[#[(`<!", 1000),
+		"CarriageReturns":      strings.Repeat("[[a\r", 2000),
+		"InvalidUTF8":          strings.Repeat("[[\xff\xfe", 2000),
+		"UnclosedFrontMatter":  "---\n" + strings.Repeat("key: value\n", 2000),
+		"UnclosedJSON":         "{\"a\":\n" + strings.Repeat("[\n", 2000),
+		"ParagraphOfBrackets":  strings.Repeat("[a\n", 2000),
+		"ParagraphOfLinkTexts": strings.Repeat("[a]\n", 2000),
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			input := input + tail
+			begin := time.Now()
+			res, _ := Parse(input)
+			if elapsed := time.Since(begin); elapsed > 5*time.Second {
+				t.Fatalf("Parse of %s took %v", name, elapsed)
+			}
+
+			if len(res.WikiLinks) == 0 || res.WikiLinks[len(res.WikiLinks)-1].Value != "tail-link" {
+				t.Fatalf("Parse of %s lost the trailing wikilink, got %d wikilinks", name, len(res.WikiLinks))
+			}
+			if len(res.Tags) == 0 || res.Tags[len(res.Tags)-1].Value != "tail-tag" {
+				t.Fatalf("Parse of %s lost the trailing tag, got %d tags", name, len(res.Tags))
+			}
+			if len(res.Links) == 0 || res.Links[len(res.Links)-1].Value != "url" {
+				t.Fatalf("Parse of %s lost the trailing link, got %d links", name, len(res.Links))
+			}
+			if len(res.Headers) == 0 || res.Headers[len(res.Headers)-1].Value != "Tail" {
+				t.Fatalf("Parse of %s lost the trailing heading, got %d headings", name, len(res.Headers))
+			}
+		})
+	}
+}
+
+func TestParseStrayBracketShouldNotSwallowLaterSymbols(t *testing.T) {
+	input := "# Title\nintro\nsee [ here\n## Next\n[[page]] #tag [x](y)"
+
+	res, err := Parse(input)
+	if len(res.Headers) != 2 {
+		failMessageInt(t, input, len(res.Headers), err, 2)
+	}
+	if len(res.WikiLinks) != 1 || len(res.Tags) != 1 || len(res.Links) != 1 {
+		failMessageInt(t, input, len(res.WikiLinks), err, 1)
+	}
+}
+
+func TestParseWikiLinkShouldNotSpanLines(t *testing.T) {
+	input := "[[first\nsecond]] [[third]]"
+
+	res, err := Parse(input)
+	if len(res.WikiLinks) != 1 || res.WikiLinks[0].Value != "third" {
+		failMessageInt(t, input, len(res.WikiLinks), err, 1)
+	}
+	if len(res.Diagnostics) != 1 || res.Diagnostics[0].Code != CodeUnterminatedWikiLink {
+		t.Fatalf("Parse(%q) diagnostics = %v, expected one unterminated wikilink", input, res.Diagnostics)
+	}
+}
+
+func TestParseLinkShouldNotLeaveParagraph(t *testing.T) {
+	input := "[text\n\n](url) [[after]]"
+
+	res, err := Parse(input)
+	if len(res.Links) != 0 {
+		failMessageString(t, input, res.Links[0].Lit, err, "")
+	}
+	if len(res.WikiLinks) != 1 {
+		failMessageInt(t, input, len(res.WikiLinks), err, 1)
+	}
+}
+
+func TestParseLinkMaySpanLinesInParagraph(t *testing.T) {
+	input := "[multi\nline](url)"
+
+	res, err := Parse(input)
+	if len(res.Links) != 1 || res.Links[0].EndLineNo != 1 {
+		failMessageInt(t, input, len(res.Links), err, 1)
+	}
+}
+
+func TestParseShouldFindLinksAfterUnclosedOpeners(t *testing.T) {
+	tests := []struct {
+		input     string
+		wikiLinks []string
+		tags      []string
+		links     []string
+	}{
+		{input: "[[a [[b]] c", wikiLinks: []string{"b"}},
+		{input: "[[[[a]] [[b]]", wikiLinks: []string{"a", "b"}},
+		{input: "#[[a #[[b]] c", tags: []string{"b"}},
+		{input: "#[[a [[b]] c", wikiLinks: []string{"b"}},
+		{input: "[a [b](c) d", links: []string{"c"}},
+		{input: "[a](b [c](d) e", links: []string{"d"}},
+		{input: "[a](b(c [d](e)", links: []string{"e"}},
+		{input: "[x](b \"[a](c\"d)", links: []string{"c\"d"}},
+	}
+
+	for _, test := range tests {
+		res, _ := Parse(test.input)
+		checkValues(t, test.input, "wikilinks", res.WikiLinks, test.wikiLinks)
+		checkValues(t, test.input, "tags", res.Tags, test.tags)
+		checkValues(t, test.input, "links", res.Links, test.links)
+	}
+}
+
+func checkValues(t *testing.T, input string, kind string, syms []Symbol, expected []string) {
+	t.Helper()
+	values := []string{}
+	for _, sym := range syms {
+		values = append(values, sym.Value)
+	}
+	if strings.Join(values, ",") != strings.Join(expected, ",") {
+		t.Errorf("Parse(%q) %s = %q, expected %q", input, kind, values, expected)
+	}
+}
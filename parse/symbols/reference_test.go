@@ -0,0 +1,163 @@
+package symbols
+
+import "testing"
+
+const referenceInput = `See [the docs][docs], [Docs][] and [docs].
+Also [missing][nope] and [just brackets].
+![logo][img]
+
+[docs]: https://example.com/docs "Documentation"
+[IMG]: <images/logo.png>
+  'Logo'
+[docs]: https://ignored.example.com`
+
+func TestParseShouldReturnDefinitions(t *testing.T) {
+	res, err := Parse(referenceInput)
+	if len(res.Definitions) != 2 {
+		failMessageInt(t, referenceInput, len(res.Definitions), err, 2)
+	}
+
+	docs := res.Definitions["docs"]
+	if docs.Type != DEFINITION || docs.Value != "https://example.com/docs" {
+		failMessageString(t, referenceInput, docs.Value, err, "https://example.com/docs")
+	}
+	if spanValue(docs.Title) != "Documentation" || spanValue(docs.Label) != "docs" {
+		failMessageString(t, referenceInput, spanValue(docs.Title), err, "Documentation")
+	}
+	if docs.LineNo != 4 || docs.CharStart != 1 || docs.CharEnd != 49 {
+		failMessageInt(t, referenceInput, docs.CharEnd, err, 49)
+	}
+
+	img := res.Definitions["img"]
+	if img.Value != "images/logo.png" || spanValue(img.Title) != "Logo" {
+		failMessageString(t, referenceInput, img.Value, err, "images/logo.png")
+	}
+	if img.LineNo != 5 || img.EndLineNo != 6 {
+		failMessageInt(t, referenceInput, img.EndLineNo, err, 6)
+	}
+}
+
+func TestParseShouldResolveReferenceLinks(t *testing.T) {
+	res, err := Parse(referenceInput)
+	if len(res.Links) != 4 {
+		failMessageInt(t, referenceInput, len(res.Links), err, 4)
+	}
+
+	tests := map[string]struct {
+		link  Symbol
+		kind  LinkKind
+		text  string
+		label string
+		start int
+	}{
+		"Full":      {res.Links[0], FULL, "the docs", "docs", 5},
+		"Collapsed": {res.Links[1], COLLAPSED, "Docs", "Docs", 23},
+		"Shortcut":  {res.Links[2], SHORTCUT, "docs", "docs", 36},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if tc.link.Kind != tc.kind {
+				failMessageString(t, referenceInput, string(tc.link.Kind), err, string(tc.kind))
+			}
+			if spanValue(tc.link.Text) != tc.text || spanValue(tc.link.Label) != tc.label {
+				failMessageString(t, referenceInput, spanValue(tc.link.Label), err, tc.label)
+			}
+			if tc.link.LineNo != 0 || tc.link.CharStart != tc.start {
+				failMessageInt(t, referenceInput, tc.link.CharStart, err, tc.start)
+			}
+			if tc.link.Value != "https://example.com/docs" || spanValue(tc.link.Title) != "Documentation" {
+				failMessageString(t, referenceInput, tc.link.Value, err, "https://example.com/docs")
+			}
+			if tc.link.Definition == nil || tc.link.Definition.LineNo != 4 {
+				t.Fatalf("Parse(%q) link %q has no definition on line 4", referenceInput, tc.link.Lit)
+			}
+			if tc.link.Destination.LineNo != 4 || tc.link.Destination.CharStart != 9 {
+				failMessageInt(t, referenceInput, tc.link.Destination.CharStart, err, 9)
+			}
+			if tc.link.Unresolved {
+				t.Fatalf("Parse(%q) link %q flagged unresolved", referenceInput, tc.link.Lit)
+			}
+		})
+	}
+}
+
+func TestParseShouldFlagUnresolvedReferences(t *testing.T) {
+	res, err := Parse(referenceInput)
+	missing := res.Links[3]
+	if missing.Lit != "[missing][nope]" || !missing.Unresolved {
+		failMessageString(t, referenceInput, missing.Lit, err, "[missing][nope]")
+	}
+	if missing.Definition != nil || missing.Destination != nil || missing.Value != "" {
+		failMessageString(t, referenceInput, missing.Value, err, "")
+	}
+	if missing.LineNo != 1 || missing.CharStart != 6 || missing.CharEnd != 21 {
+		failMessageInt(t, referenceInput, missing.CharEnd, err, 21)
+	}
+}
+
+func TestParseShouldResolveReferenceImages(t *testing.T) {
+	res, err := Parse(referenceInput)
+	if len(res.Images) != 1 {
+		failMessageInt(t, referenceInput, len(res.Images), err, 1)
+	}
+
+	img := res.Images[0]
+	if img.Kind != FULL || img.Value != "images/logo.png" || spanValue(img.Text) != "logo" {
+		failMessageString(t, referenceInput, img.Value, err, "images/logo.png")
+	}
+}
+
+func TestParseShouldNormalizeReferenceLabels(t *testing.T) {
+	input := "[Foo  Bar][]\n\n[foo\nbar]: /url"
+
+	res, err := Parse(input)
+	if len(res.Links) != 1 || res.Links[0].Value != "/url" {
+		failMessageInt(t, input, len(res.Links), err, 1)
+	}
+	if _, ok := res.Definitions["foo bar"]; !ok {
+		failMessageInt(t, input, len(res.Definitions), err, 1)
+	}
+}
+
+func TestParseShouldNotReturnDefinition(t *testing.T) {
+	tests := map[string]string{
+		"NoDestination":   "[a]:",
+		"NoColon":         "[a] /url",
+		"EmptyLabel":      "[]: /url",
+		"JunkAfterTitle":  "[a]: /url \"title\" junk",
+		"InsideParagraph": "text\n[a]: /url",
+		"IndentedCode":    "    [a]: /url",
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			res, err := Parse(input)
+			if len(res.Definitions) != 0 {
+				failMessageInt(t, input, len(res.Definitions), err, 0)
+			}
+		})
+	}
+}
+
+func TestParseDefinitionTitleShouldBeOptional(t *testing.T) {
+	input := "[a]: /url\n\"not a title\" trailing\n\n[a]"
+
+	res, err := Parse(input)
+	def := res.Definitions["a"]
+	if def.Title != nil || def.EndLineNo != 0 {
+		failMessageInt(t, input, def.EndLineNo, err, 0)
+	}
+	if len(res.Links) != 1 || res.Links[0].Value != "/url" {
+		failMessageInt(t, input, len(res.Links), err, 1)
+	}
+}
+
+func TestParseTaskCheckboxShouldNotBeLink(t *testing.T) {
+	input := "- [ ] todo\n- [x] done"
+
+	res, err := Parse(input)
+	if len(res.Links) != 0 {
+		failMessageString(t, input, res.Links[0].Lit, err, "")
+	}
+}
@@ -0,0 +1,140 @@
+package symbols
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/siasmey/markdown/internal/commonmark"
+	"github.com/siasmey/markdown/parse/ast"
+)
+
+// specDeviations lists the spec examples whose symbols are known to differ
+// from the symbols of the document tree of package ast. An example missing
+// from the list that fails is a regression; one on the list that passes
+// should be taken off it. The examples left need containers (block quotes
+// and lists) or raw HTML, which Parse does not model, read front matter or
+// wikilinks where the spec reads text, fold Unicode case in reference labels,
+// or nest links inside link text.
+var specDeviations = map[int]bool{
+	6: true, 7: true,
+	42: true, 70: true,
+	96:  true,
+	128: true, 161: true, 182: true,
+	218: true,
+	236: true, 237: true, 252: true, 257: true, 263: true, 273: true, 274: true,
+	278: true, 300: true, 313: true, 318: true, 324: true, 344: true,
+	520: true, 524: true, 526: true,
+	532: true, 533: true, 536: true, 538: true, 540: true,
+	559: true, 575: true,
+}
+
+// specSymbols describes the symbols of a document in a form that can be read
+// from both the parsed symbols and the document tree of package ast. The tree
+// is held to the spec HTML by the spec test of package ast, so the symbols
+// are compared with the spec only through it.
+type specSymbols struct {
+	Headings   []int
+	Links      []string
+	Images     []string
+	CodeBlocks []string
+	CodeSpans  []string
+}
+
+func TestParseShouldAgreeWithTreeOnSpecExamples(t *testing.T) {
+	examples, err := commonmark.Examples()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report := commonmark.NewReport()
+	deviations := []int{}
+	for _, ex := range examples {
+		res, _ := Parse(ex.Markdown)
+		got, expected := specSymbolsOf(res), specSymbolsOfTree(ast.Parse(ex.Markdown))
+		passed := reflect.DeepEqual(got, expected)
+		report.Add(ex, passed)
+
+		if !passed {
+			deviations = append(deviations, ex.Example)
+		}
+		if !passed && !specDeviations[ex.Example] {
+			t.Errorf("%s example %d: Parse(%q) returned %+v, package ast found %+v", ex.Section, ex.Example, ex.Markdown, got, expected)
+		} else if passed && specDeviations[ex.Example] {
+			t.Errorf("%s example %d now agrees with package ast, remove it from specDeviations", ex.Section, ex.Example)
+		}
+	}
+	t.Logf("CommonMark %s examples whose symbols agree with package ast by section:\n%s", commonmark.Version, report)
+	t.Logf("deviations: %s", formatExamples(deviations))
+}
+
+func specSymbolsOf(res Symbols) specSymbols {
+	syms := specSymbols{}
+	for _, h := range res.Headers {
+		syms.Headings = append(syms.Headings, headingLevel(h.Type))
+	}
+	for _, l := range res.Links {
+		// Bare URLs are an extension and references without a definition
+		// are only flagged, the spec leaves both as text.
+		if l.Kind != BARE && !l.Unresolved {
+			syms.Links = append(syms.Links, l.Value)
+		}
+	}
+	for _, img := range res.Images {
+		syms.Images = append(syms.Images, img.Value)
+	}
+	for _, c := range res.CodeBlocks {
+		syms.CodeBlocks = append(syms.CodeBlocks, c.Language+":"+c.Value)
+	}
+	for _, c := range res.CodeSpans {
+		syms.CodeSpans = append(syms.CodeSpans, c.Value)
+	}
+	return syms
+}
+
+func specSymbolsOfTree(doc *ast.Node) specSymbols {
+	syms := specSymbols{}
+	ast.Walk(doc, func(n *ast.Node, entering bool) bool {
+		if !entering {
+			return true
+		}
+		switch n.Type {
+		case ast.HEADING:
+			syms.Headings = append(syms.Headings, n.Level)
+		case ast.LINK:
+			syms.Links = append(syms.Links, n.Destination)
+		case ast.IMAGE:
+			syms.Images = append(syms.Images, n.Destination)
+		case ast.CODEBLOCK:
+			language := ""
+			if info := strings.Fields(n.Info); len(info) > 0 {
+				language = info[0]
+			}
+			syms.CodeBlocks = append(syms.CodeBlocks, language+":"+strings.TrimSuffix(n.Literal, "\n"))
+		case ast.CODE:
+			syms.CodeSpans = append(syms.CodeSpans, n.Literal)
+		}
+		return true
+	})
+	return syms
+}
+
+func headingLevel(t SymbolType) int {
+	for i, h := range headings {
+		if t == h {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+func formatExamples(examples []int) string {
+	sort.Ints(examples)
+	parts := make([]string, len(examples))
+	for i, ex := range examples {
+		parts[i] = fmt.Sprint(ex)
+	}
+	return strings.Join(parts, ", ")
+}
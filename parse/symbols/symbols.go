@@ -2,221 +2,537 @@ package symbols
 
 import (
 	"errors"
+	"io"
 	"strings"
 
 	"github.com/siasmey/markdown/parse/lexer"
 )
 
 type Symbol struct {
-	Lit       string
-	Value     string
-	CharStart int
-	CharEnd   int
-	LineNo    int
-	Type      SymbolType
+	Lit         string
+	Value       string
+	CharStart   int
+	CharEnd     int
+	Offset      int
+	EndOffset   int
+	LineNo      int
+	EndLineNo   int
+	Range       Range
+	Level       int
+	Language    string
+	Path        []string
+	Target      *Span
+	Heading     *Span
+	BlockID     *Span
+	Alias       *Span
+	Text        *Span
+	Destination *Span
+	Title       *Span
+	Kind        LinkKind
+	Label       *Span
+	Definition  *Symbol
+	Unresolved  bool
+	Width       int
+	Height      int
+	Type        SymbolType
 }
 
 type SymbolType string
 
 const (
-	HEADING1 SymbolType = "Heading1"
-	HEADING2 SymbolType = "Heading2"
-	WIKILINK SymbolType = "WikiLink"
-	LINK     SymbolType = "Link"
-	TAG      SymbolType = "Tag"
-	OTHER    SymbolType = "Other"
+	HEADING1    SymbolType = "Heading1"
+	HEADING2    SymbolType = "Heading2"
+	HEADING3    SymbolType = "Heading3"
+	HEADING4    SymbolType = "Heading4"
+	HEADING5    SymbolType = "Heading5"
+	HEADING6    SymbolType = "Heading6"
+	WIKILINK    SymbolType = "WikiLink"
+	LINK        SymbolType = "Link"
+	IMAGE       SymbolType = "Image"
+	EMBED       SymbolType = "Embed"
+	TAG         SymbolType = "Tag"
+	CODEBLOCK   SymbolType = "CodeBlock"
+	CODESPAN    SymbolType = "CodeSpan"
+	FRONTMATTER SymbolType = "FrontMatter"
+	ALIAS       SymbolType = "Alias"
+	DEFINITION  SymbolType = "Definition"
+	OTHER       SymbolType = "Other"
 )
 
+type LinkKind string
+
+const (
+	INLINE    LinkKind = "Inline"
+	FULL      LinkKind = "Full"
+	COLLAPSED LinkKind = "Collapsed"
+	SHORTCUT  LinkKind = "Shortcut"
+	AUTOLINK  LinkKind = "Autolink"
+	BARE      LinkKind = "Bare"
+)
+
+func (k LinkKind) IsReference() bool {
+	return k == FULL || k == COLLAPSED || k == SHORTCUT
+}
+
+// NewLine is the character sequence that ends the lines of a document.
+type NewLine string
+
+const (
+	LF   NewLine = "\n"
+	CRLF NewLine = "\r\n"
+	CR   NewLine = "\r"
+)
+
+// textEnd follows the text of a link when it is handed back to be parsed for
+// the symbols it holds. Scans stop at it like at the end of a paragraph.
+var textEnd = lexer.Token{TokenType: lexer.NL, LineNr: -1, Offset: -1, EndOffset: -1}
+
+var headings = []SymbolType{HEADING1, HEADING2, HEADING3, HEADING4, HEADING5, HEADING6}
+
+func (t SymbolType) IsHeading() bool {
+	for _, h := range headings {
+		if t == h {
+			return true
+		}
+	}
+	return false
+}
+
 type Symbols struct {
-	Title     Symbol
-	WikiLinks []Symbol
-	Links     []Symbol
-	Tags      []Symbol
-	Headers   []Symbol
+	Title       Symbol
+	FrontMatter *FrontMatter
+	WikiLinks   []Symbol
+	Links       []Symbol
+	Definitions map[string]Symbol
+	Images      []Symbol
+	Embeds      []Symbol
+	Tags        []Symbol
+	Aliases     []Symbol
+	Headers     []Symbol
+	CodeBlocks  []Symbol
+	CodeSpans   []Symbol
+	Diagnostics Diagnostics
+	LineCount   int
+	NewLine     NewLine // most common line ending, LF without any
 }
 
 type Parser struct {
-	s *lexer.Scanner
+	Encoding       lexer.Encoding // unit of columns, BYTES by default
+	s              *lexer.Scanner
+	queue          []lexer.Token // pushed back tokens, the next one last
+	pending        []Symbol
+	begun          bool
+	lineStart      bool
+	paragraph      bool
+	headingEnd     lexer.Token // new line ending the text of the last heading
+	underline      lexer.Token // underline of the last setext heading
+	linkText       int         // link texts being parsed, which hold no links
+	listIndent     int
+	lineHead       lexer.Token
+	prevOther      lexer.Token
+	prevSymbol     Symbol // last symbol returned by Next
+	frontMatter    *FrontMatter
+	diagnostics    Diagnostics
+	newLines       map[NewLine]int
+	firstNewLine   NewLine
+	drops          map[int]int
+	unclosedText   *gap
+	unclosedTarget *gap
+	unclosedParen  *gap
 }
 
 func NewParser(s string) *Parser {
-	return &Parser{lexer.NewScanner(strings.NewReader(s))}
+	return NewReaderParser(strings.NewReader(s))
+}
+
+func NewReaderParser(r io.Reader) *Parser {
+	return &Parser{
+		s:          lexer.NewScanner(r),
+		lineStart:  true,
+		listIndent: -1,
+	}
 }
 
 func Parse(input string) (Symbols, error) {
-	parser := NewParser(input)
+	return ParseReader(strings.NewReader(input))
+}
+
+// ParseReader parses the document read from r. If reading fails the symbols
+// found up to the failure are returned together with the read error. Problems
+// of the Markdown itself are reported in Symbols.Diagnostics only.
+func ParseReader(r io.Reader) (Symbols, error) {
+	return NewReaderParser(r).Parse()
+}
+
+// Parse collects all symbols of the document, see ParseReader.
+func (p *Parser) Parse() (Symbols, error) {
 	wikiLinks := []Symbol{}
 	links := []Symbol{}
+	images := []Symbol{}
+	embeds := []Symbol{}
+	definitions := map[string]Symbol{}
 	tags := []Symbol{}
+	aliases := []Symbol{}
 	headers := []Symbol{}
+	codeBlocks := []Symbol{}
+	codeSpans := []Symbol{}
 
 	var title Symbol
+	var readErr error
 
 	for {
-		sym, err := parser.nextSymbol()
+		sym, err := p.Next()
 		if err != nil {
+			if err != io.EOF {
+				readErr = err
+			}
 			break
-		} else if sym.Type == HEADING1 {
-			title = sym
-		} else if sym.Type == HEADING2 {
+		} else if sym.Type.IsHeading() {
+			if sym.Type == HEADING1 && title.Type == "" {
+				title = sym
+			}
 			headers = append(headers, sym)
 		} else if sym.Type == WIKILINK {
 			wikiLinks = append(wikiLinks, sym)
 		} else if sym.Type == LINK {
 			links = append(links, sym)
+		} else if sym.Type == IMAGE {
+			images = append(images, sym)
+		} else if sym.Type == EMBED {
+			embeds = append(embeds, sym)
 		} else if sym.Type == TAG {
 			tags = append(tags, sym)
+		} else if sym.Type == CODEBLOCK {
+			codeBlocks = append(codeBlocks, sym)
+		} else if sym.Type == CODESPAN {
+			codeSpans = append(codeSpans, sym)
+		} else if sym.Type == ALIAS {
+			aliases = append(aliases, sym)
+		} else if sym.Type == DEFINITION {
+			label := normalizeLabel(sym.Label.Value)
+			if _, ok := definitions[label]; !ok {
+				definitions[label] = sym
+			}
 		}
 	}
 
 	res := Symbols{
-		Title:     title,
-		WikiLinks: wikiLinks,
-		Links:     links,
-		Tags:      tags,
-		Headers:   headers,
+		Title:       title,
+		FrontMatter: p.frontMatter,
+		WikiLinks:   wikiLinks,
+		Links:       resolveReferences(links, definitions),
+		Definitions: definitions,
+		Images:      resolveReferences(images, definitions),
+		Embeds:      embeds,
+		Tags:        tags,
+		Aliases:     aliases,
+		Headers:     headers,
+		CodeBlocks:  codeBlocks,
+		CodeSpans:   codeSpans,
+		Diagnostics: p.diagnostics,
+		LineCount:   p.s.LineNr + 1,
+		NewLine:     p.newLine(),
+	}
+	return res, readErr
+}
+
+// Next returns the next symbol of the document, skipping plain text. At the
+// end of the document it returns io.EOF, or the error reading failed with.
+// Reference links are returned as found; only Parse and ParseReader resolve
+// them against the definitions of the whole document.
+func (p *Parser) Next() (Symbol, error) {
+	for {
+		sym, err := p.nextSymbol()
+		if err != nil {
+			if err := p.s.Err(); err != nil {
+				return Symbol{}, err
+			}
+			return Symbol{}, io.EOF
+		}
+		if sym.Type != OTHER {
+			sym.Range = Range{
+				Start: Position{Line: sym.LineNo, Column: sym.CharStart},
+				End:   Position{Line: sym.EndLineNo, Column: sym.CharEnd},
+			}
+			p.prevSymbol = sym
+			return sym, nil
+		}
+	}
+}
+
+// Diagnostics returns the problems found in the document so far.
+func (p *Parser) Diagnostics() Diagnostics {
+	return p.diagnostics
+}
+
+func (p *Parser) column(tk lexer.Token) int {
+	return tk.ColumnIn(p.Encoding)
+}
+
+func (p *Parser) endColumn(tk lexer.Token) int {
+	return tk.EndColumnIn(p.Encoding)
+}
+
+func (p *Parser) next() lexer.Token {
+	var tk lexer.Token
+	if n := len(p.queue); n > 0 {
+		tk = p.queue[n-1]
+		p.queue = p.queue[:n-1]
+	} else if tk = p.s.Scan(); tk.TokenType == lexer.ERROR {
+		// a failed read ends the document, Next reports the error
+		tk.TokenType = lexer.EOF
+	} else if tk.TokenType == lexer.NL {
+		p.countNewLine(NewLine(tk.Lit))
+	}
+
+	p.lineStart = tk.TokenType == lexer.NL && tk != textEnd
+	return tk
+}
+
+func (p *Parser) countNewLine(nl NewLine) {
+	if p.newLines == nil {
+		p.newLines = map[NewLine]int{}
+		p.firstNewLine = nl
+	}
+	p.newLines[nl]++
+}
+
+// newLine returns the line ending used most, the first one seen on a tie.
+func (p *Parser) newLine() NewLine {
+	best := p.firstNewLine
+	for _, nl := range []NewLine{LF, CRLF, CR} {
+		if p.newLines[nl] > p.newLines[best] {
+			best = nl
+		}
+	}
+	if best == "" {
+		return LF
+	}
+	return best
+}
+
+func (p *Parser) backup(lineStart bool, tokens ...lexer.Token) {
+	for i := len(tokens) - 1; i >= 0; i-- {
+		p.queue = append(p.queue, tokens[i])
+	}
+	p.lineStart = lineStart
+}
+
+func (p *Parser) readLine() ([]lexer.Token, bool) {
+	line := []lexer.Token{}
+	for {
+		tk := p.next()
+		if tk.TokenType == lexer.EOF {
+			return line, false
+		}
+
+		line = append(line, tk)
+		if tk.TokenType == lexer.NL {
+			return line, true
+		}
 	}
-	return res, nil
 }
 
 func (p *Parser) nextSymbol() (Symbol, error) {
-	tk := p.s.Scan()
+	if len(p.pending) > 0 {
+		sym := p.pending[0]
+		p.pending = p.pending[1:]
+		return sym, nil
+	}
+
+	if !p.begun {
+		p.begun = true
+		if sym, ok := p.parseFrontMatter(); ok {
+			return sym, nil
+		}
+	}
+
+	if p.lineStart {
+		if sym, ok := p.parseLineStart(); ok {
+			return sym, nil
+		}
+	}
+
+	tk := p.next()
+	if tk == textEnd {
+		p.linkText--
+		return Symbol{Type: OTHER}, nil
+	}
 
 	switch tk.TokenType {
 	case lexer.HASH:
-		return p.parseHashStart(tk)
+		if sym, ok := p.parseTag(tk); ok {
+			return sym, nil
+		}
+		if p.isHashStart(tk) {
+			return p.parseHashStart(tk)
+		}
+		return p.other(tk), nil
 	case lexer.LEFTBRK:
 		return p.parseLink(tk)
+	case lexer.BANG:
+		if sym, ok := p.parseImage(tk); ok {
+			return sym, nil
+		}
+		return p.other(tk), nil
+	case lexer.TICK:
+		if sym, ok := p.parseCodeSpan(tk); ok {
+			return sym, nil
+		}
+		return p.other(tk), nil
+	case lexer.TEXT:
+		if p.linkText > 0 {
+			return p.other(tk), nil
+		}
+		if sym, ok := p.parseBareURL(tk); ok {
+			return sym, nil
+		}
+		return p.other(tk), nil
+	case lexer.ILLEGAL:
+		if escaped, ok := p.scanEscape(tk); ok {
+			sym := p.other(tk)
+			p.pending = append(p.pending, p.other(escaped))
+			return sym, nil
+		}
+		if tk.Lit == "<" && p.linkText == 0 {
+			if sym, ok := p.parseAutolink(tk); ok {
+				return sym, nil
+			}
+		}
+		return p.other(tk), nil
 	case lexer.EOF:
 		return Symbol{}, errors.New("Nothing left to parse")
 	default:
-		return Symbol{
-			Type:      OTHER,
-			Lit:       tk.Lit,
-			Value:     tk.Lit,
-			LineNo:    tk.LineNr,
-			CharStart: tk.Column,
-			CharEnd:   tk.Column + tk.Length,
-		}, nil
-	}
-}
-
-func (p *Parser) parseLink(start lexer.Token) (Symbol, error) {
-	charStart := start.Column
-	charEnd := start.Column + start.Length
-	lineNr := start.LineNr
-	lit := start.Lit
-	linkType := LINK
-	val := ""
-	pairs := 1
-
-	for {
-		if tk := p.s.Scan(); tk.TokenType == lexer.EOF {
-			break
-		} else if tk.TokenType == lexer.LEFTBRK {
-			lit += tk.Lit
-			charEnd += tk.Length
-			pairs += 1
-			linkType = WIKILINK
-		} else if tk.TokenType == lexer.TEXT {
-			lit += tk.Lit
-			charEnd += tk.Length
-			val += tk.Lit
-		} else if tk.TokenType == lexer.WS {
-			lit += tk.Lit
-			charEnd += tk.Length
-			val += tk.Lit
-		} else if tk.TokenType == lexer.ILLEGAL {
-			lit += tk.Lit
-			charEnd += tk.Length
-			val += tk.Lit
-		} else if tk.TokenType == lexer.RIGHTBRK {
-			lit += tk.Lit
-			pairs -= 1
-			charEnd += tk.Length
-
-			if pairs < 1 {
-				if linkType == WIKILINK {
-					break
-				}
-			}
-		} else if tk.TokenType == lexer.LEFTPRN {
-			lit += tk.Lit
-			val = ""
-			charEnd += tk.Length
-		} else if tk.TokenType == lexer.RIGHTPRN {
-			lit += tk.Lit
-			charEnd += tk.Length
-			break
-		}
+		return p.other(tk), nil
 	}
+}
 
+func (p *Parser) other(tk lexer.Token) Symbol {
+	p.prevOther = tk
 	return Symbol{
-		Type:      linkType,
-		Lit:       lit,
-		Value:     val,
-		LineNo:    lineNr,
-		CharStart: charStart,
-		CharEnd:   charEnd,
-	}, nil
+		Type:      OTHER,
+		Lit:       tk.Lit,
+		Value:     tk.Lit,
+		LineNo:    tk.LineNr,
+		EndLineNo: tk.LineNr,
+		CharStart: p.column(tk),
+		CharEnd:   p.endColumn(tk),
+		Offset:    tk.Offset,
+		EndOffset: tk.EndOffset,
+	}
+}
+
+func (p *Parser) isHashStart(start lexer.Token) bool {
+	after := p.next()
+	p.backup(false, after)
+
+	if start.Lit == "#" && after.TokenType == lexer.LEFTBRK {
+		return true
+	}
+	return start == p.lineHead && isHeadingStart(start, after)
 }
 
 func (p *Parser) parseHashStart(start lexer.Token) (Symbol, error) {
-	charStart := start.Column
-	charEnd := start.Column + start.Length
-	lineNr := start.LineNr
-	lit := start.Lit
-	val := ""
-	hashType := HEADING1
+	level := start.Length
+	hashType := OTHER
 	gotTrailingWs := false
 	scopes := 0
+	consumed := []lexer.Token{}
+	lit := []lexer.Token{start}
+	val := []lexer.Token{}
 
-	if start.Length == 2 {
-		hashType = HEADING2
+	if level <= len(headings) {
+		hashType = headings[level-1]
+	} else {
+		level = 0
 	}
 
 	for {
-		if tk := p.s.Scan(); tk.TokenType == lexer.EOF {
+		tk := p.next()
+		if tk.TokenType == lexer.EOF {
 			break
-		} else if tk.TokenType == lexer.NL {
+		}
+		consumed = append(consumed, tk)
+
+		if tk.TokenType == lexer.NL {
 			break
-		} else if start.Lit == "#" && tk.TokenType == lexer.LEFTBRK {
-			hashType = TAG
-			lit += tk.Lit
-			charEnd += tk.Length
+		}
+		lit = append(lit, tk)
 
+		if start.Lit == "#" && tk.TokenType == lexer.LEFTBRK && (hashType == TAG || len(consumed) == 1) {
+			hashType = TAG
+			level = 0
 			scopes += 1
+			if len(consumed) == 1 && !p.mayClose(tk, 1) {
+				break
+			}
 		} else if tk.TokenType == lexer.RIGHTBRK {
 			scopes -= 1
-			lit += tk.Lit
-			charEnd += tk.Length
+			if hashType != TAG {
+				val = append(val, tk)
+			}
 
 			if hashType == TAG && scopes < 1 {
 				break
 			}
-		} else if tk.TokenType == lexer.WS {
-			if gotTrailingWs {
-				lit += tk.Lit
-				charEnd += tk.Length
-				val += tk.Lit
-			} else {
-				gotTrailingWs = true
-				lit += tk.Lit
-				charEnd += tk.Length
-			}
+		} else if tk.TokenType == lexer.WS && !gotTrailingWs && scopes == 0 {
+			gotTrailingWs = true
 		} else {
-			lit += tk.Lit
-			val += tk.Lit
-			charEnd += tk.Length
+			val = append(val, tk)
+		}
+	}
+
+	if hashType == TAG && scopes > 0 {
+		p.diagnose(ERROR, CodeUnterminatedTag, p.tokenRange(start, consumed[0]), "unterminated tag, missing ]")
+		p.markUnclosed(consumed)
+		p.backup(false, consumed[1:]...)
+		sym := p.other(start)
+		sym.Lit += consumed[0].Lit
+		sym.Value = sym.Lit
+		sym.CharEnd = p.endColumn(consumed[0])
+		sym.EndOffset = consumed[0].EndOffset
+		return sym, nil
+	}
+
+	value := literal(val)
+	var path []string
+	if hashType.IsHeading() {
+		value = trimClosingHashes(value)
+		p.paragraph = false
+		// the heading text is parsed again for the symbols it contains
+		if n := len(consumed); n > 0 && consumed[n-1].TokenType == lexer.NL {
+			p.headingEnd = consumed[n-1]
 		}
+		p.backup(false, consumed...)
+	} else if hashType == TAG {
+		path = tagPath(value)
 	}
 
+	last := lit[len(lit)-1]
 	return Symbol{
 		Type:      hashType,
-		Lit:       lit,
-		Value:     val,
-		LineNo:    lineNr,
-		CharStart: charStart,
-		CharEnd:   charEnd,
+		Lit:       literal(lit),
+		Value:     value,
+		LineNo:    start.LineNr,
+		EndLineNo: start.LineNr,
+		Level:     level,
+		Path:      path,
+		CharStart: p.column(start),
+		CharEnd:   p.endColumn(last),
+		Offset:    start.Offset,
+		EndOffset: last.EndOffset,
 	}, nil
 }
+
+func trimClosingHashes(val string) string {
+	val = strings.TrimRight(val, " \t")
+	trimmed := strings.TrimRight(val, "#")
+
+	if trimmed == "" {
+		return trimmed
+	}
+	if trimmed != val && strings.ContainsAny(trimmed[len(trimmed)-1:], " \t") {
+		return strings.TrimRight(trimmed, " \t")
+	}
+	return val
+}
@@ -1,8 +1,14 @@
 package symbols
 
 import (
+	"errors"
+	"io"
 	"reflect"
+	"strings"
 	"testing"
+	"testing/iotest"
+
+	"github.com/siasmey/markdown/parse/lexer"
 )
 
 func TestParseShouldReturnTitle(t *testing.T) {
@@ -305,6 +311,249 @@ func TestParseShouldReturnHeadersLineNo(t *testing.T) {
 	}
 }
 
+func TestParseShouldReturnHeadingLevels(t *testing.T) {
+	tests := map[string]struct {
+		input string
+		want  SymbolType
+		level int
+	}{
+		"Heading1": {"# one", HEADING1, 1},
+		"Heading2": {"## two", HEADING2, 2},
+		"Heading3": {"### three", HEADING3, 3},
+		"Heading4": {"#### four", HEADING4, 4},
+		"Heading5": {"##### five", HEADING5, 5},
+		"Heading6": {"###### six", HEADING6, 6},
+		"Empty1":   {"#", HEADING1, 1},
+		"Empty2":   {"##", HEADING2, 2},
+		"LastLine": {"a\n###", HEADING3, 3},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			res, err := Parse(tc.input)
+			if len(res.Headers) != 1 {
+				failMessageInt(t, tc.input, len(res.Headers), err, 1)
+			}
+			check := res.Headers[0]
+			if check.Type != tc.want {
+				failMessageType(t, tc.input, check.Type, err, tc.want)
+			}
+			if check.Level != tc.level {
+				failMessageInt(t, tc.input, check.Level, err, tc.level)
+			}
+		})
+	}
+}
+
+func TestParseShouldReturnHeadingsInContainers(t *testing.T) {
+	tests := map[string]string{
+		"> # Quote heading": "Quote heading",
+		"- # Item heading":  "Item heading",
+		"1. # Ordered":      "Ordered",
+		"2) # Paren":        "Paren",
+		"> - > ## Nested":   "Nested",
+	}
+
+	for input, want := range tests {
+		res, err := Parse(input)
+		if len(res.Headers) != 1 || res.Headers[0].Value != want {
+			failMessageInt(t, input, len(res.Headers), err, 1)
+		}
+	}
+
+	for _, input := range []string{">#x", "-# x", "- -#x", "1.# x"} {
+		if res, err := Parse(input); len(res.Headers) != 0 {
+			failMessageInt(t, input, len(res.Headers), err, 0)
+		}
+	}
+}
+
+func TestParseShouldNotReturnHeadingForSevenHashes(t *testing.T) {
+	input := "####### seven"
+	expected := 0
+
+	res, err := Parse(input)
+	if len(res.Headers) != expected {
+		failMessageInt(t, input, len(res.Headers), err, expected)
+	}
+}
+
+func TestParseShouldNotOverwriteTitleWithLowerHeadings(t *testing.T) {
+	input := `# Title
+### Third
+#### Fourth`
+	expected := "Title"
+
+	res, err := Parse(input)
+	if res.Title.Value != expected {
+		failMessageString(t, input, res.Title.Value, err, expected)
+	}
+}
+
+func TestParseShouldKeepFirstHeading1AsTitle(t *testing.T) {
+	input := `# First
+# Second`
+	expected := "First"
+
+	res, err := Parse(input)
+	if res.Title.Value != expected {
+		failMessageString(t, input, res.Title.Value, err, expected)
+	}
+}
+
+func TestParseShouldReturnAllHeadingLevelsInHeaders(t *testing.T) {
+	input := `# one
+## two
+### three
+#### four
+##### five
+###### six`
+	expected := []string{"one", "two", "three", "four", "five", "six"}
+
+	res, err := Parse(input)
+	if len(res.Headers) != len(expected) {
+		failMessageInt(t, input, len(res.Headers), err, len(expected))
+	}
+	for i, want := range expected {
+		if res.Headers[i].Value != want {
+			failMessageString(t, input, res.Headers[i].Value, err, want)
+		}
+		if res.Headers[i].Level != i+1 {
+			failMessageInt(t, input, res.Headers[i].Level, err, i+1)
+		}
+	}
+}
+
+func TestParseShouldStripClosingHashesFromHeading(t *testing.T) {
+	tests := map[string]struct {
+		input string
+		want  string
+	}{
+		"Closing":         {"## heading ##", "heading"},
+		"ClosingLonger":   {"### heading #######  ", "heading"},
+		"NotClosing":      {"## heading#", "heading#"},
+		"OnlyHashes":      {"### ###", ""},
+		"TrailingSpaces":  {"## heading   ", "heading"},
+		"HashInsideValue": {"## C# and F#", "C# and F#"},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			res, err := Parse(tc.input)
+			check := res.Headers[0]
+			if check.Value != tc.want {
+				failMessageString(t, tc.input, check.Value, err, tc.want)
+			}
+			if check.Lit != tc.input {
+				failMessageString(t, tc.input, check.Lit, err, tc.input)
+			}
+		})
+	}
+}
+
+func TestParseShouldReturnSymbolsInsideHeading(t *testing.T) {
+	tests := map[string]struct {
+		input string
+		want  []SymbolType
+		lit   string
+	}{
+		"Tag":      {"## Section #tag", []SymbolType{HEADING2, TAG}, "#tag"},
+		"WikiLink": {"# T [[x]]", []SymbolType{HEADING1, WIKILINK}, "[[x]]"},
+		"Link":     {"# [a](b)", []SymbolType{HEADING1, LINK}, "[a](b)"},
+		"CodeSpan": {"### a `b` ###", []SymbolType{HEADING3, CODESPAN}, "`b`"},
+		"Embed":    {"# ![[img.png]]\ntext", []SymbolType{HEADING1, EMBED}, "![[img.png]]"},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			p := NewParser(tc.input)
+			got := []SymbolType{}
+			var last Symbol
+			for {
+				sym, err := p.Next()
+				if err != nil {
+					break
+				}
+				got = append(got, sym.Type)
+				last = sym
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("Parse(%q) returned %v, expected %v", tc.input, got, tc.want)
+			}
+			if last.Lit != tc.lit || tc.input[last.Offset:last.EndOffset] != tc.lit {
+				t.Errorf("Parse(%q) returned %q at %d:%d, expected %q", tc.input, last.Lit, last.Offset, last.EndOffset, tc.lit)
+			}
+		})
+	}
+}
+
+func TestParseShouldEndHeadingSymbolsWithTheLine(t *testing.T) {
+	for _, input := range []string{"# T [[x\ny]]", "# a `b\nc`", "# [a\nb](c)", "# [a](b\nc)"} {
+		res, _ := Parse(input)
+		if n := len(res.WikiLinks) + len(res.CodeSpans) + len(res.Links); n != 0 {
+			t.Errorf("Parse(%q) returned %d symbols spanning the heading, expected none", input, n)
+		}
+	}
+}
+
+func TestParseShouldReturnSetextHeading(t *testing.T) {
+	tests := map[string]struct {
+		input string
+		value string
+		lit   string
+		level int
+	}{
+		"Level1":    {"Title *x*\n===\n#tag", "Title *x*", "Title *x*\n===", 1},
+		"Level2":    {"Sub\n---", "Sub", "Sub\n---", 2},
+		"TwoLines":  {"a\n  b  \n---", "a\nb", "a\n  b  \n---", 2},
+		"Indented":  {"   Title\n ==  ", "Title", "Title\n ==", 1},
+		"AfterText": {"# H\nTitle\n=", "Title", "Title\n=", 1},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			res, err := Parse(tc.input)
+			if len(res.Headers) == 0 {
+				failMessageInt(t, tc.input, 0, err, 1)
+			}
+
+			h := res.Headers[len(res.Headers)-1]
+			if h.Value != tc.value {
+				failMessageString(t, tc.input, h.Value, err, tc.value)
+			}
+			if h.Lit != tc.lit || tc.input[h.Offset:h.EndOffset] != tc.lit {
+				failMessageString(t, tc.input, h.Lit, err, tc.lit)
+			}
+			if h.Level != tc.level {
+				failMessageInt(t, tc.input, h.Level, err, tc.level)
+			}
+		})
+	}
+}
+
+func TestParseShouldNotReturnSetextHeading(t *testing.T) {
+	for _, input := range []string{"a\n\n---", "- a\n---", "***\n---", "> a\n===", "a\n= =", "```\na\n```\n---"} {
+		res, _ := Parse(input)
+		for _, h := range res.Headers {
+			if h.EndLineNo != h.LineNo {
+				t.Errorf("Parse(%q) returned setext heading %q, expected none", input, h.Lit)
+			}
+		}
+	}
+}
+
+func TestParseShouldEndSymbolsAtSetextUnderline(t *testing.T) {
+	input := "a\nb [[c\n---\nd]] `e\n===\nf`"
+
+	res, err := Parse(input)
+	if len(res.WikiLinks) != 0 || len(res.CodeSpans) != 0 {
+		failMessageInt(t, input, len(res.WikiLinks)+len(res.CodeSpans), err, 0)
+	}
+	if len(res.Headers) != 2 {
+		failMessageInt(t, input, len(res.Headers), err, 2)
+	}
+}
+
 func TestParseShouldReturnCombinationsTitle(t *testing.T) {
 	input := `# test header
 [Http link](http://test.com) [[arst1234]]
@@ -372,13 +621,253 @@ func TestParseShouldReturnCombinationsHeaders(t *testing.T) {
 	expected := "test second header # huh"
 
 	res, err := Parse(input)
-	check := res.Headers[0]
+	check := res.Headers[1]
 
 	if check.Value != expected {
 		failMessageString(t, input, check.Value, err, expected)
 	}
 }
 
+func TestParseShouldReturnUnicodeTitle(t *testing.T) {
+	input := "# Café 日本語 🎉"
+	expected := "Café 日本語 🎉"
+
+	res, err := Parse(input)
+	if res.Title.Value != expected {
+		failMessageString(t, input, res.Title.Value, err, expected)
+	}
+}
+
+func TestParseShouldReturnUnicodeWikilinkValue(t *testing.T) {
+	input := "[[Zürich über 東京]]"
+	expected := "Zürich über 東京"
+
+	res, err := Parse(input)
+	if res.WikiLinks[0].Value != expected {
+		failMessageString(t, input, res.WikiLinks[0].Value, err, expected)
+	}
+}
+
+func TestParseShouldReturnUnicodeWikilinkCharEnd(t *testing.T) {
+	input := "[[Zürich]]"
+	expected := len(input) + 1
+
+	res, err := Parse(input)
+	if res.WikiLinks[0].CharEnd != expected {
+		failMessageInt(t, input, res.WikiLinks[0].CharEnd, err, expected)
+	}
+}
+
+func TestParseReaderShouldMatchParse(t *testing.T) {
+	input := "# Title\n[[wiki]] #tag [link][ref]\n\n[ref]: https://example.com\n"
+
+	expected, _ := Parse(input)
+	res, err := ParseReader(iotest.OneByteReader(strings.NewReader(input)))
+	if err != nil {
+		t.Fatalf("ParseReader(%q) returned error %v", input, err)
+	}
+	if !reflect.DeepEqual(res, expected) {
+		t.Fatalf("ParseReader(%q) = %+v, expected %+v", input, res, expected)
+	}
+}
+
+func TestParseReaderShouldReturnReadError(t *testing.T) {
+	input := "# Title\n[[wiki]]\n"
+	failure := errors.New("connection reset")
+	reader := io.MultiReader(strings.NewReader(input), iotest.ErrReader(failure))
+
+	res, err := ParseReader(reader)
+	if !errors.Is(err, failure) {
+		t.Fatalf("ParseReader(%q) returned error %v, expected %v", input, err, failure)
+	}
+	if res.Title.Value != "Title" || len(res.WikiLinks) != 1 {
+		t.Fatalf("ParseReader(%q) = %+v, expected the symbols read before the error", input, res)
+	}
+}
+
+func TestParserNextShouldYieldSymbolsInOrder(t *testing.T) {
+	input := "# Title\nsome [[wiki]] text #tag\n"
+	expected := []SymbolType{HEADING1, WIKILINK, TAG}
+
+	parser := NewReaderParser(strings.NewReader(input))
+	result := []SymbolType{}
+	for {
+		sym, err := parser.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("Next() returned error %v", err)
+		}
+		result = append(result, sym.Type)
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Fatalf("Next() on %q yielded %v, expected %v", input, result, expected)
+	}
+}
+
+func TestParserNextShouldReturnReadError(t *testing.T) {
+	failure := errors.New("corrupt archive")
+	parser := NewReaderParser(io.MultiReader(strings.NewReader("[[wiki]] "), iotest.ErrReader(failure)))
+
+	if sym, err := parser.Next(); err != nil || sym.Type != WIKILINK {
+		t.Fatalf("Next() = %v, %v, expected the wikilink", sym.Type, err)
+	}
+	if _, err := parser.Next(); !errors.Is(err, failure) {
+		t.Fatalf("Next() returned error %v, expected %v", err, failure)
+	}
+}
+
+func TestParseOffsetsShouldSliceInput(t *testing.T) {
+	input := "---\ntags: [one, two]\n---\n# Café title\n\nsee [[Page#Part|alias]] and #nested/tag,\n" +
+		"a [link](https://example.com \"Title\") ![alt](img.png) ![[image.png|300]]\n" +
+		"`code` <https://go.dev> https://example.org.\n\n```go\nx := 1\n```\n\n[ref]: /url\n"
+
+	res, err := Parse(input)
+	if err != nil {
+		t.Fatalf("Parse(%q) returned error %v", input, err)
+	}
+
+	groups := [][]Symbol{res.Headers, res.WikiLinks, res.Links, res.Images, res.Embeds, res.Tags, res.CodeBlocks, res.CodeSpans}
+	for _, group := range groups {
+		if len(group) == 0 {
+			t.Fatalf("Parse(%q) is missing symbols, got %+v", input, res)
+		}
+		for _, sym := range group {
+			lit := sym.Lit
+			if sym.Type == TAG && sym.LineNo == 1 {
+				lit = sym.Value
+			}
+			if got := input[sym.Offset:sym.EndOffset]; got != lit {
+				t.Errorf("Parse(%q) %s offsets %d to %d slice %q, expected %q", input, sym.Type, sym.Offset, sym.EndOffset, got, lit)
+			}
+			for _, span := range []*Span{sym.Target, sym.Heading, sym.Alias, sym.Text, sym.Title} {
+				if span != nil && input[span.Offset:span.EndOffset] != span.Value {
+					t.Errorf("Parse(%q) %s span offsets %d to %d slice %q, expected %q", input, sym.Type, span.Offset, span.EndOffset, input[span.Offset:span.EndOffset], span.Value)
+				}
+			}
+		}
+	}
+
+	def := res.Definitions["ref"]
+	if got := input[def.Offset:def.EndOffset]; got != def.Lit {
+		t.Errorf("Parse(%q) definition offsets slice %q, expected %q", input, got, def.Lit)
+	}
+	fm, _ := NewParser(input).Next()
+	if got := input[fm.Offset:fm.EndOffset]; fm.Type != FRONTMATTER || got != fm.Lit {
+		t.Errorf("Parse(%q) front matter offsets slice %q, expected %q", input, got, fm.Lit)
+	}
+}
+
+func TestParseShouldCountColumnsInEncoding(t *testing.T) {
+	input := "---\ntags: [日本, 🎉x]\n---\n🎉 é [[ページ|エイリアス]] #tag [[open\n"
+
+	// front matter tags, wikilink, its target and alias, inline tag and the
+	// unterminated wikilink diagnostic
+	tests := map[lexer.Encoding][]int{
+		lexer.BYTES: {8, 14, 16, 21, 9, 38, 11, 20, 21, 36, 39, 43, 44},
+		lexer.RUNES: {8, 10, 12, 14, 5, 18, 7, 10, 11, 16, 19, 23, 24},
+		lexer.UTF16: {8, 10, 12, 15, 6, 19, 8, 11, 12, 17, 20, 24, 25},
+	}
+	for encoding, want := range tests {
+		parser := NewParser(input)
+		parser.Encoding = encoding
+		res, _ := parser.Parse()
+
+		wiki, tag := res.WikiLinks[0], res.Tags[2]
+		got := []int{
+			res.Tags[0].CharStart, res.Tags[0].CharEnd, res.Tags[1].CharStart, res.Tags[1].CharEnd,
+			wiki.CharStart, wiki.CharEnd, wiki.Target.CharStart, wiki.Target.CharEnd, wiki.Alias.CharStart, wiki.Alias.CharEnd,
+			tag.CharStart, tag.CharEnd, res.Diagnostics[0].Range.Start.Column,
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Parse(%q) in %v got columns %v, expected %v", input, encoding, got, want)
+		}
+	}
+}
+
+func TestParseShouldCountLinesForAllNewLineStyles(t *testing.T) {
+	lines := []string{"---", "tags: a", "---", "# Title", "", "```", "code", "```", "[[link]] #tag"}
+
+	for _, nl := range []NewLine{LF, CRLF, CR} {
+		input := strings.Join(lines, string(nl))
+
+		res, err := Parse(input)
+		if err != nil {
+			t.Fatalf("Parse(%q) returned error %v", input, err)
+		}
+		if res.NewLine != nl || res.LineCount != len(lines) {
+			t.Errorf("Parse(%q) new line %q and %d lines, expected %q and %d", input, res.NewLine, res.LineCount, nl, len(lines))
+		}
+		if res.Title.LineNo != 3 || res.CodeBlocks[0].LineNo != 5 || res.CodeBlocks[0].EndLineNo != 7 || res.CodeBlocks[0].Value != "code" {
+			t.Errorf("Parse(%q) title on line %d, code block %+v", input, res.Title.LineNo, res.CodeBlocks[0])
+		}
+		if res.WikiLinks[0].LineNo != 8 || res.Tags[1].LineNo != 8 || res.Tags[1].CharStart != 10 {
+			t.Errorf("Parse(%q) wikilink %+v, tag %+v, expected both on line 8", input, res.WikiLinks[0], res.Tags[1])
+		}
+	}
+}
+
+func TestParseShouldReportMostCommonNewLine(t *testing.T) {
+	tests := map[string]NewLine{
+		"":               LF,
+		"one line":       LF,
+		"a\r\nb\r\nc\nd": CRLF,
+		"a\nb\r\nc":      LF,
+		"a\r\nb\nc":      CRLF,
+		"a\rb\rc\r\n":    CR,
+	}
+
+	for input, expected := range tests {
+		res, _ := Parse(input)
+		if res.NewLine != expected {
+			t.Errorf("Parse(%q) new line = %q, expected %q", input, res.NewLine, expected)
+		}
+	}
+}
+
+func TestParseShouldReturnRanges(t *testing.T) {
+	rng := func(startLine, startColumn, endLine, endColumn int) Range {
+		return Range{Start: Position{Line: startLine, Column: startColumn}, End: Position{Line: endLine, Column: endColumn}}
+	}
+	tests := map[string]struct {
+		input    string
+		symbol   func(Symbols) Range
+		expected Range
+	}{
+		"WikiLink":       {"x [[a]]", func(s Symbols) Range { return s.WikiLinks[0].Range }, rng(0, 3, 0, 8)},
+		"Link":           {"[multi\nline text](url)", func(s Symbols) Range { return s.Links[0].Range }, rng(0, 1, 1, 16)},
+		"LinkText":       {"[multi\nline text](url)", func(s Symbols) Range { return s.Links[0].Text.Range }, rng(0, 2, 1, 10)},
+		"CodeBlock":      {"```go\ncode\n```", func(s Symbols) Range { return s.CodeBlocks[0].Range }, rng(0, 1, 2, 4)},
+		"CodeSpan":       {"`a\nb`", func(s Symbols) Range { return s.CodeSpans[0].Range }, rng(0, 1, 1, 3)},
+		"Definition":     {"[ref]: /url\n  \"Title\"\n", func(s Symbols) Range { return s.Definitions["ref"].Range }, rng(0, 1, 1, 10)},
+		"DefinitionUsed": {"[ref]\n\n[ref]: /url\n  \"Title\"\n", func(s Symbols) Range { return s.Links[0].Definition.Range }, rng(2, 1, 3, 10)},
+		"ImageText":      {"![alt\ntext|300](img.png)", func(s Symbols) Range { return s.Images[0].Text.Range }, rng(0, 3, 1, 5)},
+		"FrontMatterTag": {"---\ntags:\n  - a\n---\n", func(s Symbols) Range { return s.Tags[0].Range }, rng(2, 5, 2, 6)},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			res, err := Parse(tc.input)
+			if err != nil {
+				t.Fatalf("Parse(%q) returned error %v", tc.input, err)
+			}
+			if got := tc.symbol(res); got != tc.expected {
+				t.Fatalf("Parse(%q) range = %+v, expected %+v", tc.input, got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestParserNextShouldReturnFrontMatterRange(t *testing.T) {
+	input := "---\ntitle: x\n---\n"
+	expected := Range{Start: Position{Line: 0, Column: 1}, End: Position{Line: 2, Column: 4}}
+
+	sym, err := NewParser(input).Next()
+	if err != nil || sym.Type != FRONTMATTER || sym.Range != expected {
+		t.Fatalf("Next() on %q = %+v, %v, expected front matter at %+v", input, sym, err, expected)
+	}
+}
+
 func itemExists(slice interface{}, item interface{}) bool {
 	s := reflect.ValueOf(slice)
 
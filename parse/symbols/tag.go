@@ -0,0 +1,136 @@
+package symbols
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/siasmey/markdown/parse/lexer"
+)
+
+func (p *Parser) parseTag(start lexer.Token) (Symbol, bool) {
+	if start.Lit != "#" || p.followsText(start) {
+		return Symbol{}, false
+	}
+
+	consumed := []lexer.Token{}
+	tokens := []lexer.Token{}
+
+	for {
+		tk := p.next()
+		if tk.TokenType == lexer.ILLEGAL && tk.Lit == "/" && len(tokens) > 0 {
+			consumed = append(consumed, tk)
+			tokens = append(tokens, tk)
+			continue
+		}
+		if tk.TokenType != lexer.TEXT {
+			p.backup(false, tk)
+			break
+		}
+
+		consumed = append(consumed, tk)
+		n := tagPrefix(tk.Lit)
+		if n == 0 {
+			p.backup(false, tk)
+			consumed = consumed[:len(consumed)-1]
+			break
+		}
+		if n < len(tk.Lit) {
+			head, rest := splitToken(tk, n)
+			tokens = append(tokens, head)
+			p.backup(false, rest)
+			break
+		}
+		tokens = append(tokens, tk)
+	}
+
+	for len(tokens) > 0 && tokens[len(tokens)-1].Lit == "/" {
+		tokens = tokens[:len(tokens)-1]
+		p.backup(false, consumed[len(consumed)-1])
+		consumed = consumed[:len(consumed)-1]
+	}
+
+	value := literal(tokens)
+	if strings.IndexFunc(value, isAlphaRune) < 0 {
+		p.backup(false, consumed...)
+		return Symbol{}, false
+	}
+
+	last := tokens[len(tokens)-1]
+	return Symbol{
+		Type:      TAG,
+		Lit:       start.Lit + value,
+		Value:     value,
+		Path:      tagPath(value),
+		LineNo:    start.LineNr,
+		EndLineNo: start.LineNr,
+		CharStart: p.column(start),
+		CharEnd:   p.endColumn(last),
+		Offset:    start.Offset,
+		EndOffset: last.EndOffset,
+	}, true
+}
+
+// A hash glued to the end of a word, as in C# or a URL fragment, is not a tag.
+// The word may also end a symbol, as the tag before the second hash of #a#b.
+func (p *Parser) followsText(tk lexer.Token) bool {
+	prev := p.prevOther
+	glued := prev.TokenType == lexer.TEXT || prev.TokenType == lexer.ILLEGAL
+	if glued && prev.LineNr == tk.LineNr && prev.Column+prev.Length == tk.Column {
+		return true
+	}
+
+	last := p.prevSymbol
+	if last.Type == "" || last.EndOffset != tk.Offset || last.Lit == "" {
+		return false
+	}
+	ch, _ := utf8.DecodeLastRuneInString(last.Lit)
+	return !strings.ContainsRune(" \t\r\n#`~[]()!", ch)
+}
+
+func tagPrefix(lit string) int {
+	for i, ch := range lit {
+		if !isTagRune(ch) {
+			return i
+		}
+	}
+	return len(lit)
+}
+
+func isTagRune(ch rune) bool {
+	return isAlphaRune(ch) || unicode.IsNumber(ch) || ch == '_' || ch == '-' || ch == '\u200d'
+}
+
+func isAlphaRune(ch rune) bool {
+	return unicode.IsLetter(ch) || unicode.IsMark(ch)
+}
+
+func splitToken(tk lexer.Token, n int) (lexer.Token, lexer.Token) {
+	head := tk
+	head.Lit = tk.Lit[:n]
+	head.Length = n
+	head.RuneLength = utf8.RuneCountInString(head.Lit)
+	head.UTF16Length = lexer.Width(head.Lit, lexer.UTF16)
+	head.EndOffset = tk.Offset + n
+
+	rest := tk
+	rest.Lit = tk.Lit[n:]
+	rest.Length = tk.Length - n
+	rest.Column = tk.Column + n
+	rest.RuneLength = tk.RuneLength - head.RuneLength
+	rest.RuneColumn = tk.RuneColumn + head.RuneLength
+	rest.UTF16Length = tk.UTF16Length - head.UTF16Length
+	rest.UTF16Column = tk.UTF16Column + head.UTF16Length
+	rest.Offset = tk.Offset + n
+	return head, rest
+}
+
+func tagPath(value string) []string {
+	path := []string{}
+	for _, segment := range strings.Split(value, "/") {
+		if segment = strings.TrimSpace(segment); segment != "" {
+			path = append(path, segment)
+		}
+	}
+	return path
+}
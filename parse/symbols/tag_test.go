@@ -0,0 +1,130 @@
+package symbols
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseShouldReturnInlineTag(t *testing.T) {
+	tests := map[string]struct {
+		input string
+		lit   string
+		value string
+		path  []string
+	}{
+		"Simple":        {"some #project here", "#project", "project", []string{"project"}},
+		"LineStart":     {"#project", "#project", "project", []string{"project"}},
+		"Nested":        {"a #tag/sub/child b", "#tag/sub/child", "tag/sub/child", []string{"tag", "sub", "child"}},
+		"Dashes":        {"#tag-with_dashes", "#tag-with_dashes", "tag-with_dashes", []string{"tag-with_dashes"}},
+		"Digits":        {"#y1984", "#y1984", "y1984", []string{"y1984"}},
+		"Unicode":       {"#café/日本", "#café/日本", "café/日本", []string{"café", "日本"}},
+		"Punctuation":   {"see #todo.", "#todo", "todo", []string{"todo"}},
+		"Comma":         {"#one, two", "#one", "one", []string{"one"}},
+		"TrailingSlash": {"#parent/ x", "#parent", "parent", []string{"parent"}},
+		"Parenthesis":   {"(#idea)", "#idea", "idea", []string{"idea"}},
+		"ListItem":      {"- #task", "#task", "task", []string{"task"}},
+		"GluedHash":     {"#a#b", "#a", "a", []string{"a"}},
+		"AfterWikiLink": {"[[x]]#b", "#b", "b", []string{"b"}},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			res, err := Parse(tc.input)
+			if len(res.Tags) != 1 {
+				failMessageInt(t, tc.input, len(res.Tags), err, 1)
+			}
+
+			tag := res.Tags[0]
+			if tag.Lit != tc.lit {
+				failMessageString(t, tc.input, tag.Lit, err, tc.lit)
+			}
+			if tag.Value != tc.value {
+				failMessageString(t, tc.input, tag.Value, err, tc.value)
+			}
+			if !reflect.DeepEqual(tag.Path, tc.path) {
+				t.Fatalf("Parse(%q) path = %q, expected %q", tc.input, tag.Path, tc.path)
+			}
+		})
+	}
+}
+
+func TestParseShouldReturnInlineTagPosition(t *testing.T) {
+	input := "first line\nsome #tag/sub here"
+
+	res, err := Parse(input)
+	tag := res.Tags[0]
+	if tag.LineNo != 1 || tag.EndLineNo != 1 {
+		failMessageInt(t, input, tag.LineNo, err, 1)
+	}
+	if tag.CharStart != 6 {
+		failMessageInt(t, input, tag.CharStart, err, 6)
+	}
+	if tag.CharEnd != 14 {
+		failMessageInt(t, input, tag.CharEnd, err, 14)
+	}
+}
+
+func TestParseShouldNotReturnInlineTag(t *testing.T) {
+	tests := map[string]string{
+		"Heading":     "# project",
+		"Numeric":     "issue #42",
+		"HashSpace":   "a # b",
+		"DoubleHash":  "a ##tag",
+		"GluedToWord": "C#tag",
+		"URLFragment": "http://example.com/#anchor",
+		"CodeSpan":    "`#code`",
+		"CodeBlock":   "```\n#code\n```",
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			res, err := Parse(input)
+			if len(res.Tags) != 0 {
+				failMessageString(t, input, res.Tags[0].Lit, err, "")
+			}
+		})
+	}
+}
+
+func TestParseInlineTagShouldNotBecomeTitle(t *testing.T) {
+	input := "#project\n# Title"
+
+	res, err := Parse(input)
+	if res.Title.Value != "Title" {
+		failMessageString(t, input, res.Title.Value, err, "Title")
+	}
+	if len(res.Headers) != 1 {
+		failMessageInt(t, input, len(res.Headers), err, 1)
+	}
+}
+
+func TestParseHashWithoutSpaceShouldNotBeHeading(t *testing.T) {
+	input := "#5 bolt"
+
+	res, err := Parse(input)
+	if len(res.Headers) != 0 {
+		failMessageString(t, input, res.Headers[0].Lit, err, "")
+	}
+}
+
+func TestParseShouldReturnBracketTagPath(t *testing.T) {
+	input := "#[[multi word/child]]"
+
+	res, err := Parse(input)
+	want := []string{"multi word", "child"}
+	if !reflect.DeepEqual(res.Tags[0].Path, want) {
+		failMessageString(t, input, res.Tags[0].Value, err, "multi word/child")
+	}
+}
+
+func TestParseHeadingWithBracketsShouldNotBeTag(t *testing.T) {
+	input := "# Title [[link]]"
+
+	res, err := Parse(input)
+	if len(res.Tags) != 0 {
+		failMessageString(t, input, res.Tags[0].Lit, err, "")
+	}
+	if res.Title.Value != "Title [[link]]" {
+		failMessageString(t, input, res.Title.Value, err, "Title [[link]]")
+	}
+}
@@ -0,0 +1,134 @@
+package symbols
+
+import "github.com/siasmey/markdown/parse/lexer"
+
+type Span struct {
+	Value     string
+	LineNo    int
+	CharStart int
+	CharEnd   int
+	Offset    int
+	EndOffset int
+	Range     Range
+}
+
+func (p *Parser) newSpan(tokens []lexer.Token) *Span {
+	tokens = trimSpace(tokens)
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	first := tokens[0]
+	last := tokens[len(tokens)-1]
+	return &Span{
+		Value:     literal(tokens),
+		LineNo:    first.LineNr,
+		CharStart: p.column(first),
+		CharEnd:   p.endColumn(last),
+		Offset:    first.Offset,
+		EndOffset: last.EndOffset,
+		Range:     p.tokenRange(first, last),
+	}
+}
+
+func trimSpace(tokens []lexer.Token) []lexer.Token {
+	for len(tokens) > 0 && isSpace(tokens[0]) {
+		tokens = tokens[1:]
+	}
+	for len(tokens) > 0 && isSpace(tokens[len(tokens)-1]) {
+		tokens = tokens[:len(tokens)-1]
+	}
+	return tokens
+}
+
+func isSpace(tk lexer.Token) bool {
+	return tk.TokenType == lexer.WS || tk.TokenType == lexer.NL
+}
+
+// parseWikiLink reads the rest of a wikilink after its opening brackets. A
+// wikilink never spans lines; an unterminated one is reported from first, the
+// bang of an embed or else the first bracket, and everything after the first
+// bracket is handed back so scanning resumes right behind it.
+func (p *Parser) parseWikiLink(first lexer.Token, start lexer.Token, second lexer.Token) (Symbol, bool) {
+	pairs := 2
+	inner := []lexer.Token{}
+	consumed := []lexer.Token{second}
+
+	kind := "wikilink"
+	if first != start {
+		kind = "embed"
+	}
+	if !p.mayClose(second, 2) {
+		p.diagnose(ERROR, CodeUnterminatedWikiLink, p.tokenRange(first, second), "unterminated %s, missing ]]", kind)
+		p.backup(false, second)
+		return Symbol{}, false
+	}
+
+	for pairs > 0 {
+		tk := p.next()
+		consumed = append(consumed, tk)
+
+		switch tk.TokenType {
+		case lexer.EOF, lexer.NL:
+			p.diagnose(ERROR, CodeUnterminatedWikiLink, p.tokenRange(first, second), "unterminated %s, missing ]]", kind)
+			p.markUnclosed(consumed)
+			p.backup(false, consumed...)
+			return Symbol{}, false
+		case lexer.LEFTBRK:
+			pairs += 1
+		case lexer.RIGHTBRK:
+			pairs -= 1
+		default:
+			inner = append(inner, tk)
+		}
+	}
+
+	closing := consumed[len(consumed)-1]
+	sym := Symbol{
+		Type:      WIKILINK,
+		Lit:       start.Lit + literal(consumed),
+		Value:     literal(inner),
+		LineNo:    start.LineNr,
+		EndLineNo: start.LineNr,
+		CharStart: p.column(start),
+		CharEnd:   p.endColumn(closing),
+		Offset:    start.Offset,
+		EndOffset: closing.EndOffset,
+	}
+	p.setWikiLinkFields(&sym, inner)
+	return sym, true
+}
+
+// Inside tables the alias pipe is escaped as \| so it does not split the row.
+func (p *Parser) setWikiLinkFields(sym *Symbol, inner []lexer.Token) {
+	page := inner
+	for i, tk := range inner {
+		if tk.TokenType != lexer.ILLEGAL || tk.Lit != "|" {
+			continue
+		}
+
+		page = inner[:i]
+		if i > 0 && inner[i-1].Lit == "\\" {
+			page = inner[:i-1]
+		}
+		sym.Alias = p.newSpan(inner[i+1:])
+		break
+	}
+
+	target := page
+	for i, tk := range page {
+		if tk.TokenType != lexer.HASH {
+			continue
+		}
+
+		target = page[:i]
+		fragment := trimSpace(page[i+1:])
+		if len(fragment) > 0 && fragment[0].Lit == "^" {
+			sym.BlockID = p.newSpan(fragment[1:])
+		} else {
+			sym.Heading = p.newSpan(fragment)
+		}
+		break
+	}
+	sym.Target = p.newSpan(target)
+}
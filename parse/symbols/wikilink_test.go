@@ -0,0 +1,107 @@
+package symbols
+
+import "testing"
+
+func spanValue(s *Span) string {
+	if s == nil {
+		return "<nil>"
+	}
+	return s.Value
+}
+
+func TestParseShouldReturnWikiLinkFields(t *testing.T) {
+	tests := map[string]struct {
+		input   string
+		target  string
+		heading string
+		blockID string
+		alias   string
+	}{
+		"Page":           {"[[Page]]", "Page", "<nil>", "<nil>", "<nil>"},
+		"Alias":          {"[[Page|Alias]]", "Page", "<nil>", "<nil>", "Alias"},
+		"Heading":        {"[[Page#Heading]]", "Page", "Heading", "<nil>", "<nil>"},
+		"NestedHeading":  {"[[Page#One#Two]]", "Page", "One#Two", "<nil>", "<nil>"},
+		"Block":          {"[[Page#^block-id]]", "Page", "<nil>", "block-id", "<nil>"},
+		"HeadingAlias":   {"[[Page#Heading|Shown text]]", "Page", "Heading", "<nil>", "Shown text"},
+		"BlockAlias":     {"[[Page#^abc|see]]", "Page", "<nil>", "abc", "see"},
+		"SamePage":       {"[[#Heading]]", "<nil>", "Heading", "<nil>", "<nil>"},
+		"SamePageBlock":  {"[[#^abc]]", "<nil>", "<nil>", "abc", "<nil>"},
+		"Spaces":         {"[[ My Page # Sub | Alias ]]", "My Page", "Sub", "<nil>", "Alias"},
+		"EscapedPipe":    {"[[Page\\|Alias]]", "Page", "<nil>", "<nil>", "Alias"},
+		"Parenthesis":    {"[[Page (draft)]]", "Page (draft)", "<nil>", "<nil>", "<nil>"},
+		"PathTarget":     {"[[folder/Page.md]]", "folder/Page.md", "<nil>", "<nil>", "<nil>"},
+		"EmptyAlias":     {"[[Page|]]", "Page", "<nil>", "<nil>", "<nil>"},
+		"AliasWithHash":  {"[[Page|C# notes]]", "Page", "<nil>", "<nil>", "C# notes"},
+		"UnicodeHeading": {"[[Café#Über]]", "Café", "Über", "<nil>", "<nil>"},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			res, err := Parse(tc.input)
+			if len(res.WikiLinks) != 1 {
+				failMessageInt(t, tc.input, len(res.WikiLinks), err, 1)
+			}
+
+			link := res.WikiLinks[0]
+			if got := spanValue(link.Target); got != tc.target {
+				failMessageString(t, tc.input, got, err, tc.target)
+			}
+			if got := spanValue(link.Heading); got != tc.heading {
+				failMessageString(t, tc.input, got, err, tc.heading)
+			}
+			if got := spanValue(link.BlockID); got != tc.blockID {
+				failMessageString(t, tc.input, got, err, tc.blockID)
+			}
+			if got := spanValue(link.Alias); got != tc.alias {
+				failMessageString(t, tc.input, got, err, tc.alias)
+			}
+		})
+	}
+}
+
+func TestParseShouldReturnWikiLinkFieldRanges(t *testing.T) {
+	input := "x\nsee [[Page#Heading|Alias]] and [[Other#^id]]"
+
+	res, err := Parse(input)
+	tests := map[string]struct {
+		span  *Span
+		start int
+		end   int
+	}{
+		"Target":  {res.WikiLinks[0].Target, 7, 11},
+		"Heading": {res.WikiLinks[0].Heading, 12, 19},
+		"Alias":   {res.WikiLinks[0].Alias, 20, 25},
+		"Other":   {res.WikiLinks[1].Target, 34, 39},
+		"BlockID": {res.WikiLinks[1].BlockID, 41, 43},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if tc.span.LineNo != 1 {
+				failMessageInt(t, input, tc.span.LineNo, err, 1)
+			}
+			if tc.span.CharStart != tc.start {
+				failMessageInt(t, input, tc.span.CharStart, err, tc.start)
+			}
+			if tc.span.CharEnd != tc.end {
+				failMessageInt(t, input, tc.span.CharEnd, err, tc.end)
+			}
+		})
+	}
+}
+
+func TestParseWikiLinkShouldKeepHashInLiteral(t *testing.T) {
+	input := "[[Page#Heading]]"
+
+	res, err := Parse(input)
+	check := res.WikiLinks[0]
+	if check.Lit != input {
+		failMessageString(t, input, check.Lit, err, input)
+	}
+	if check.Value != "Page#Heading" {
+		failMessageString(t, input, check.Value, err, "Page#Heading")
+	}
+	if check.CharEnd != 17 {
+		failMessageInt(t, input, check.CharEnd, err, 17)
+	}
+}
@@ -0,0 +1,437 @@
+// Package html renders Markdown document trees to HTML.
+package html
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/siasmey/markdown/parse/ast"
+	"github.com/siasmey/markdown/parse/symbols"
+)
+
+// Renderer writes the HTML of a document. The zero value renders plain
+// CommonMark; NewRenderer also adds heading ids, wikilinks and tag links.
+//
+// Link and image URLs are filtered, and raw HTML is left out unless Unsafe is
+// set. Documents from untrusted sources that are rendered with their HTML
+// need it sanitized.
+type Renderer struct {
+	HeadingIDs bool
+	// Unsafe writes the raw HTML of the document as is.
+	Unsafe bool
+	// WikiLinkURL returns the URL of a wikilink or embed, or "" to render
+	// only its text. Convert leaves wikilinks to CommonMark when it is nil.
+	WikiLinkURL func(link symbols.Symbol) string
+	// TagURL returns the URL of a tag. Convert leaves tags as text when it is
+	// nil.
+	TagURL func(tag symbols.Symbol) string
+}
+
+func NewRenderer() *Renderer {
+	return &Renderer{
+		HeadingIDs:  true,
+		WikiLinkURL: WikiLinkURL,
+		TagURL:      TagURL,
+	}
+}
+
+// Convert parses input and renders it, leaving out its front matter. The
+// wikilinks, embeds and tags are those symbols.Parse finds in the document,
+// spliced into its tree.
+func (r *Renderer) Convert(w io.Writer, input string) error {
+	res, _ := symbols.Parse(input)
+	source := input
+	if res.FrontMatter != nil {
+		source = skipLines(input, res.FrontMatter.EndLineNo+1)
+	}
+
+	s := r.newState()
+	doc := ast.Parse(source)
+	ast.Splice(doc, source, s.inlines(res, len(input)-len(source)))
+	return s.render(w, doc)
+}
+
+// Render writes the HTML of doc. Its WIKILINK, EMBED and TAG nodes are
+// rendered as Convert splices them in.
+func (r *Renderer) Render(w io.Writer, doc *ast.Node) error {
+	return r.newState().render(w, doc)
+}
+
+func (r *Renderer) newState() *state {
+	return &state{Renderer: r, ids: map[string]bool{}, suffixes: map[string]int{}, embeds: map[*ast.Node]symbols.Symbol{}}
+}
+
+// inlines returns the nodes of the wikilinks, embeds and tags of res the
+// renderer links, in source order. Their offsets are taken from base on.
+func (s *state) inlines(res symbols.Symbols, base int) []*ast.Node {
+	syms := []symbols.Symbol{}
+	if s.WikiLinkURL != nil {
+		syms = append(syms, res.WikiLinks...)
+		syms = append(syms, res.Embeds...)
+	}
+	if s.TagURL != nil {
+		syms = append(syms, res.Tags...)
+	}
+	sort.Slice(syms, func(i, j int) bool { return syms[i].Offset < syms[j].Offset })
+
+	nodes := []*ast.Node{}
+	for _, sym := range syms {
+		if sym.Offset < base {
+			continue
+		}
+		n := s.inline(sym)
+		n.Range.Start.Offset, n.Range.End.Offset = sym.Offset-base, sym.EndOffset-base
+		nodes = append(nodes, n)
+	}
+	return nodes
+}
+
+// inline returns the node of a wikilink, embed or tag. Embedded images become
+// EMBED nodes, any other embed, which cannot be inlined, a WIKILINK.
+func (s *state) inline(sym symbols.Symbol) *ast.Node {
+	n := &ast.Node{Type: ast.TAG, Literal: sym.Lit}
+	if sym.Type == symbols.TAG {
+		n.Destination = s.TagURL(sym)
+		return n
+	}
+
+	n.Type = ast.WIKILINK
+	text := sym.Value
+	if sym.Type == symbols.EMBED && sym.Target != nil && isImage(sym.Target.Value) {
+		n.Type = ast.EMBED
+		text = sym.Target.Value
+		s.embeds[n] = sym
+	}
+	if sym.Alias != nil {
+		text = sym.Alias.Value
+	}
+	n.Destination = s.WikiLinkURL(sym)
+	n.AppendChild(&ast.Node{Type: ast.TEXT, Literal: text})
+	return n
+}
+
+type state struct {
+	*Renderer
+	buf bytes.Buffer
+	ids map[string]bool
+	// suffixes holds the last number appended to each repeated slug.
+	suffixes map[string]int
+	// links counts the links being rendered, which must not nest.
+	links int
+	// embeds holds the symbols of the EMBED nodes Convert splices in, for
+	// their size.
+	embeds map[*ast.Node]symbols.Symbol
+}
+
+func (s *state) render(w io.Writer, doc *ast.Node) error {
+	ast.Walk(doc, s.node)
+	_, err := w.Write(s.buf.Bytes())
+	return err
+}
+
+func (s *state) node(n *ast.Node, entering bool) bool {
+	switch n.Type {
+	case ast.PARAGRAPH:
+		if item := n.Parent; item.Type == ast.ITEM && item.Parent.Tight {
+			return true
+		}
+		if entering {
+			s.cr()
+		}
+		s.tag(entering, "<p>", "</p>\n")
+	case ast.HEADING:
+		if entering {
+			s.cr()
+			fmt.Fprintf(&s.buf, "<h%d%s>", n.Level, s.headingID(n))
+		} else {
+			fmt.Fprintf(&s.buf, "</h%d>\n", n.Level)
+		}
+	case ast.BLOCKQUOTE:
+		s.cr()
+		s.tag(entering, "<blockquote>\n", "</blockquote>\n")
+	case ast.LIST:
+		s.cr()
+		name := "ul"
+		if n.Ordered {
+			name = "ol"
+		}
+		open := fmt.Sprintf("<%s>\n", name)
+		if n.Ordered && n.Start != 1 {
+			open = fmt.Sprintf("<ol start=\"%d\">\n", n.Start)
+		}
+		s.tag(entering, open, fmt.Sprintf("</%s>\n", name))
+	case ast.ITEM:
+		s.tag(entering, "<li>", "</li>\n")
+	case ast.THEMATICBREAK:
+		s.cr()
+		s.buf.WriteString("<hr />\n")
+		return false
+	case ast.CODEBLOCK:
+		s.cr()
+		s.buf.WriteString("<pre><code")
+		if info := strings.Fields(n.Info); len(info) > 0 {
+			fmt.Fprintf(&s.buf, " class=\"language-%s\"", escape(info[0]))
+		}
+		fmt.Fprintf(&s.buf, ">%s</code></pre>\n", escape(n.Literal))
+		return false
+	case ast.HTMLBLOCK:
+		s.cr()
+		s.buf.WriteString(s.rawHTML(n.Literal))
+		s.cr()
+		return false
+	case ast.TEXT:
+		s.buf.WriteString(escape(n.Literal))
+		return false
+	case ast.SOFTBREAK:
+		s.buf.WriteString("\n")
+		return false
+	case ast.HARDBREAK:
+		s.buf.WriteString("<br />\n")
+		return false
+	case ast.EMPHASIS:
+		s.tag(entering, "<em>", "</em>")
+	case ast.STRONG:
+		s.tag(entering, "<strong>", "</strong>")
+	case ast.CODE:
+		fmt.Fprintf(&s.buf, "<code>%s</code>", escape(n.Literal))
+		return false
+	case ast.HTMLINLINE:
+		s.buf.WriteString(s.rawHTML(n.Literal))
+		return false
+	case ast.LINK:
+		if entering {
+			s.links++
+			fmt.Fprintf(&s.buf, "<a href=\"%s\"", escape(safeURL(n.Destination)))
+			if n.Title != "" {
+				fmt.Fprintf(&s.buf, " title=\"%s\"", escape(n.Title))
+			}
+			s.buf.WriteString(">")
+		} else {
+			s.links--
+			s.buf.WriteString("</a>")
+		}
+	case ast.IMAGE:
+		fmt.Fprintf(&s.buf, "<img src=\"%s\" alt=\"%s\"", escape(safeURL(n.Destination)), escape(n.Text()))
+		if n.Title != "" {
+			fmt.Fprintf(&s.buf, " title=\"%s\"", escape(n.Title))
+		}
+		s.buf.WriteString(" />")
+		return false
+	case ast.WIKILINK:
+		if s.links > 0 {
+			s.buf.WriteString(escape(n.Literal))
+			return false
+		}
+		if n.Destination != "" {
+			s.tag(entering, fmt.Sprintf("<a href=\"%s\" class=\"wikilink\">", escape(safeURL(n.Destination))), "</a>")
+		}
+	case ast.EMBED:
+		fmt.Fprintf(&s.buf, "<img src=\"%s\" alt=\"%s\"", escape(safeURL(n.Destination)), escape(n.Text()))
+		if embed := s.embeds[n]; embed.Width > 0 {
+			fmt.Fprintf(&s.buf, " width=\"%d\"", embed.Width)
+		}
+		if embed := s.embeds[n]; embed.Height > 0 {
+			fmt.Fprintf(&s.buf, " height=\"%d\"", embed.Height)
+		}
+		s.buf.WriteString(" />")
+		return false
+	case ast.TAG:
+		if s.links > 0 || n.Destination == "" {
+			s.buf.WriteString(escape(n.Literal))
+		} else {
+			fmt.Fprintf(&s.buf, "<a href=\"%s\" class=\"tag\">%s</a>", escape(safeURL(n.Destination)), escape(n.Literal))
+		}
+		return false
+	}
+	return true
+}
+
+// rawHTML returns the raw HTML of the document to write.
+func (s *state) rawHTML(lit string) string {
+	if !s.Unsafe {
+		return "<!-- raw HTML omitted -->"
+	}
+	return lit
+}
+
+func (s *state) cr() {
+	if s.buf.Len() > 0 && s.buf.Bytes()[s.buf.Len()-1] != '\n' {
+		s.buf.WriteByte('\n')
+	}
+}
+
+func (s *state) tag(entering bool, open string, close string) {
+	if entering {
+		s.buf.WriteString(open)
+	} else {
+		s.buf.WriteString(close)
+	}
+}
+
+// headingID returns the id attribute of a heading, numbering repeated ids.
+func (s *state) headingID(n *ast.Node) string {
+	if !s.HeadingIDs {
+		return ""
+	}
+	slug := Slug(n.Text())
+	if slug == "" {
+		return ""
+	}
+
+	id := slug
+	if s.ids[id] {
+		n := s.suffixes[slug]
+		for s.ids[id] {
+			n++
+			id = fmt.Sprintf("%s-%d", slug, n)
+		}
+		s.suffixes[slug] = n
+	}
+	s.ids[id] = true
+	return fmt.Sprintf(" id=\"%s\"", escape(id))
+}
+
+var imageExtensions = []string{".avif", ".bmp", ".gif", ".jpeg", ".jpg", ".png", ".svg", ".webp"}
+
+func isImage(target string) bool {
+	ext := strings.ToLower(path.Ext(target))
+	for _, image := range imageExtensions {
+		if ext == image {
+			return true
+		}
+	}
+	return false
+}
+
+// Slug turns text into a heading id: lower case letters, digits, hyphens and
+// underscores, with spaces replaced by hyphens.
+func Slug(text string) string {
+	var sb strings.Builder
+	for _, ch := range strings.ToLower(strings.TrimSpace(text)) {
+		switch {
+		case unicode.IsLetter(ch) || unicode.IsNumber(ch) || ch == '-' || ch == '_':
+			sb.WriteRune(ch)
+		case unicode.IsSpace(ch):
+			sb.WriteByte('-')
+		}
+	}
+	return sb.String()
+}
+
+// WikiLinkURL is the default URL of a wikilink: the slug of every directory
+// and the page of the target with .html appended, followed by the slug of the
+// heading or the block id. A link without target points into the document.
+// Embedded files with an extension keep their path.
+func WikiLinkURL(link symbols.Symbol) string {
+	href := ""
+	if link.Target != nil {
+		segments := strings.Split(link.Target.Value, "/")
+		file := link.Type == symbols.EMBED && path.Ext(link.Target.Value) != ""
+		for i, segment := range segments {
+			if file {
+				segments[i] = url.PathEscape(segment)
+			} else {
+				segments[i] = Slug(segment)
+			}
+		}
+		href = strings.Join(segments, "/")
+		if !file {
+			href += ".html"
+		}
+	}
+
+	if link.Heading != nil {
+		href += "#" + Slug(link.Heading.Value)
+	} else if link.BlockID != nil {
+		href += "#^" + url.PathEscape(link.BlockID.Value)
+	}
+	return href
+}
+
+// TagURL is the default URL of a tag, /tags/ followed by its lower case path.
+func TagURL(tag symbols.Symbol) string {
+	segments := make([]string, len(tag.Path))
+	for i, segment := range tag.Path {
+		segments[i] = url.PathEscape(strings.ToLower(segment))
+	}
+	return "/tags/" + strings.Join(segments, "/")
+}
+
+var escaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "\"", "&quot;")
+
+func escape(s string) string {
+	return escaper.Replace(s)
+}
+
+// safeURL percent-encodes the characters of a destination that are not
+// allowed in URLs, keeping existing escapes. Script, file and data URLs other
+// than images are dropped.
+func safeURL(s string) string {
+	if !isSafeURL(s) {
+		return ""
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '%' && i+2 < len(s) && isHexDigit(s[i+1]) && isHexDigit(s[i+2]):
+			sb.WriteByte(c)
+		case c < 0x80 && (isAlphaNumeric(c) || strings.IndexByte(";/?:@&=+$,-_.!~*'()#", c) >= 0):
+			sb.WriteByte(c)
+		default:
+			fmt.Fprintf(&sb, "%%%02X", c)
+		}
+	}
+	return sb.String()
+}
+
+var (
+	unsafeSchemes    = []string{"javascript:", "vbscript:", "file:", "data:"}
+	safeDataPrefixes = []string{"data:image/png", "data:image/gif", "data:image/jpeg", "data:image/webp"}
+)
+
+func isSafeURL(s string) bool {
+	// Browsers ignore tabs and line breaks in URLs, and the spaces around them.
+	s = strings.ToLower(strings.TrimSpace(strings.NewReplacer("\t", "", "\n", "", "\r", "").Replace(s)))
+	for _, prefix := range safeDataPrefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	for _, scheme := range unsafeSchemes {
+		if strings.HasPrefix(s, scheme) {
+			return false
+		}
+	}
+	return true
+}
+
+// skipLines returns what follows the first n lines of s.
+func skipLines(s string, n int) string {
+	for ; n > 0 && s != ""; n-- {
+		i := strings.IndexAny(s, "\r\n")
+		if i < 0 {
+			return ""
+		}
+		if strings.HasPrefix(s[i:], "\r\n") {
+			i++
+		}
+		s = s[i+1:]
+	}
+	return s
+}
+
+func isAlphaNumeric(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+func isHexDigit(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F'
+}
@@ -0,0 +1,191 @@
+package html
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/siasmey/markdown/parse/ast"
+	"github.com/siasmey/markdown/parse/symbols"
+)
+
+func render(t *testing.T, r *Renderer, input string) string {
+	t.Helper()
+	var sb strings.Builder
+	if err := r.Convert(&sb, input); err != nil {
+		t.Fatal(err)
+	}
+	return sb.String()
+}
+
+func TestRenderShouldAddHeadingIDs(t *testing.T) {
+	input := "# Hello, *World*!\n\n## Hello World\n\n## Hello World-1\n\n## Hello World\n\n## ???"
+	expected := "<h1 id=\"hello-world\">Hello, <em>World</em>!</h1>\n" +
+		"<h2 id=\"hello-world-1\">Hello World</h2>\n" +
+		"<h2 id=\"hello-world-1-1\">Hello World-1</h2>\n" +
+		"<h2 id=\"hello-world-2\">Hello World</h2>\n" +
+		"<h2>???</h2>\n"
+
+	if got := render(t, NewRenderer(), input); got != expected {
+		t.Errorf("Render(%q) returned %q, expected %q", input, got, expected)
+	}
+}
+
+func TestRenderShouldDropUnsafeURLs(t *testing.T) {
+	tests := map[string]string{
+		"[a](javascript:alert(1))":        "<p><a href=\"\">a</a></p>\n",
+		"[a](<JavaScript\t:alert(1)>)":    "<p><a href=\"\">a</a></p>\n",
+		"![a](data:text/html,x)":          "<p><img src=\"\" alt=\"a\" /></p>\n",
+		"![a](data:image/png;base64,AAA)": "<p><img src=\"data:image/png;base64,AAA\" alt=\"a\" /></p>\n",
+		"[a](https://example.com/a b)":    "<p>[a](https://example.com/a b)</p>\n",
+		"[a](</a b?c=ä>)":                 "<p><a href=\"/a%20b?c=%C3%A4\">a</a></p>\n",
+	}
+
+	for input, expected := range tests {
+		if got := render(t, NewRenderer(), input); got != expected {
+			t.Errorf("Render(%q) returned %q, expected %q", input, got, expected)
+		}
+	}
+}
+
+func TestRenderShouldLinkWikiLinks(t *testing.T) {
+	tests := map[string]string{
+		"See [[Some Note]].":            "<p>See <a href=\"some-note.html\" class=\"wikilink\">Some Note</a>.</p>\n",
+		"[[notes/Some Note|the note]]":  "<p><a href=\"notes/some-note.html\" class=\"wikilink\">the note</a></p>\n",
+		"[[Note#Big Heading]]":          "<p><a href=\"note.html#big-heading\" class=\"wikilink\">Note#Big Heading</a></p>\n",
+		"[[#Heading]] and [[Note#^b1]]": "<p><a href=\"#heading\" class=\"wikilink\">#Heading</a> and <a href=\"note.html#%5Eb1\" class=\"wikilink\">Note#^b1</a></p>\n",
+		"`[[Code]]` [x [[Note]]](/url)": "<p><code>[[Code]]</code> <a href=\"/url\">x [[Note]]</a></p>\n",
+	}
+
+	for input, expected := range tests {
+		if got := render(t, NewRenderer(), input); got != expected {
+			t.Errorf("Render(%q) returned %q, expected %q", input, got, expected)
+		}
+	}
+}
+
+func TestRenderShouldLinkOnlyWikiLinksAndTagsOfTheTree(t *testing.T) {
+	tests := map[string]string{
+		"\\#x \\[[y]]":                    "<p>#x [[y]]</p>\n",
+		"&#35;x &#91;[y]]":                "<p>#x [[y]]</p>\n",
+		"[[a *b* c]]":                     "<p><a href=\"a-b-c.html\" class=\"wikilink\">a *b* c</a></p>\n",
+		"*a*\n{\"x\": 1} [[y]]":           "<p><em>a</em>\n{&quot;x&quot;: 1} <a href=\"y.html\" class=\"wikilink\">y</a></p>\n",
+		"*a*\n\\# h [[z]]":                "<p><em>a</em>\n# h <a href=\"z.html\" class=\"wikilink\">z</a></p>\n",
+		"[[Page]]\n\n[Page]: http://evil": "<p><a href=\"page.html\" class=\"wikilink\">Page</a></p>\n",
+		"a`#b`#c [#d](/u)":                "<p>a<code>#b</code><a href=\"/tags/c\" class=\"tag\">#c</a> <a href=\"/u\">#d</a></p>\n",
+		"#a#b":                            "<p><a href=\"/tags/a\" class=\"tag\">#a</a>#b</p>\n",
+	}
+
+	for input, expected := range tests {
+		if got := render(t, NewRenderer(), input); got != expected {
+			t.Errorf("Render(%q) returned %q, expected %q", input, got, expected)
+		}
+	}
+}
+
+func TestRenderShouldEmbedImages(t *testing.T) {
+	tests := map[string]string{
+		"![[img.png]]":           "<p><img src=\"img.png\" alt=\"img.png\" /></p>\n",
+		"![[a b/c.JPG|200x100]]": "<p><img src=\"a%20b/c.JPG\" alt=\"a b/c.JPG\" width=\"200\" height=\"100\" /></p>\n",
+		"![[pic.gif|a cat]]":     "<p><img src=\"pic.gif\" alt=\"a cat\" /></p>\n",
+		"![[Note]]":              "<p><a href=\"note.html\" class=\"wikilink\">Note</a></p>\n",
+		"![[doc.pdf]]":           "<p><a href=\"doc.pdf\" class=\"wikilink\">doc.pdf</a></p>\n",
+	}
+
+	for input, expected := range tests {
+		if got := render(t, NewRenderer(), input); got != expected {
+			t.Errorf("Render(%q) returned %q, expected %q", input, got, expected)
+		}
+	}
+}
+
+func TestRenderShouldOmitRawHTML(t *testing.T) {
+	tests := map[string]struct {
+		input    string
+		safe     string
+		expected string
+	}{
+		"Block":  {"<div>\n<script>x</script>\n</div>", "<!-- raw HTML omitted -->\n", "<div>\n<script>x</script>\n</div>\n"},
+		"Inline": {"a <a href=\"javascript:x\">b</a>", "<p>a <!-- raw HTML omitted -->b<!-- raw HTML omitted --></p>\n", "<p>a <a href=\"javascript:x\">b</a></p>\n"},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := render(t, NewRenderer(), tc.input); got != tc.safe {
+				t.Errorf("Render(%q) returned %q, expected %q", tc.input, got, tc.safe)
+			}
+			if got := render(t, &Renderer{Unsafe: true}, tc.input); got != tc.expected {
+				t.Errorf("Render(%q) with Unsafe returned %q, expected %q", tc.input, got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestRenderShouldResolveWikiLinksWithWikiLinkURL(t *testing.T) {
+	r := &Renderer{WikiLinkURL: func(link symbols.Symbol) string {
+		if link.Target.Value == "Missing" {
+			return ""
+		}
+		return "/notes/" + link.Target.Value
+	}}
+	input := "[[Found]] [[Missing|gone]] #tag"
+	expected := "<p><a href=\"/notes/Found\" class=\"wikilink\">Found</a> gone #tag</p>\n"
+
+	if got := render(t, r, input); got != expected {
+		t.Errorf("Render(%q) returned %q, expected %q", input, got, expected)
+	}
+}
+
+func TestRenderShouldLinkTags(t *testing.T) {
+	input := "# Title #Project\n\nA #project/Active tag, C# and `#code`."
+	expected := "<h1 id=\"title-project\">Title <a href=\"/tags/project\" class=\"tag\">#Project</a></h1>\n" +
+		"<p>A <a href=\"/tags/project/active\" class=\"tag\">#project/Active</a> tag, C# and <code>#code</code>.</p>\n"
+
+	if got := render(t, NewRenderer(), input); got != expected {
+		t.Errorf("Render(%q) returned %q, expected %q", input, got, expected)
+	}
+}
+
+func TestRenderShouldReturnWriteError(t *testing.T) {
+	err := NewRenderer().Render(failingWriter{}, ast.Parse("text"))
+	if !errors.Is(err, errWrite) {
+		t.Errorf("Render returned %v, expected %v", err, errWrite)
+	}
+}
+
+func TestConvertShouldSkipFrontMatter(t *testing.T) {
+	tests := map[string]string{
+		"---\ntitle: Note\n---\n# Note":   "<h1 id=\"note\">Note</h1>\n",
+		"+++\r\ntitle = 1\r\n+++\r\ntext": "<p>text</p>\n",
+		"---\n\n# Note":                   "<hr />\n<h1 id=\"note\">Note</h1>\n",
+	}
+
+	for input, expected := range tests {
+		var sb strings.Builder
+		if err := NewRenderer().Convert(&sb, input); err != nil || sb.String() != expected {
+			t.Errorf("Convert(%q) returned %q, %v, expected %q", input, sb.String(), err, expected)
+		}
+	}
+}
+
+func TestSlugShouldKeepLettersAndDigits(t *testing.T) {
+	tests := map[string]string{
+		"Hello, World!":     "hello-world",
+		" Über  uns ":       "über--uns",
+		"snake_case-2 (v1)": "snake_case-2-v1",
+	}
+
+	for input, expected := range tests {
+		if got := Slug(input); got != expected {
+			t.Errorf("Slug(%q) returned %q, expected %q", input, got, expected)
+		}
+	}
+}
+
+var errWrite = errors.New("write failed")
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errWrite
+}
@@ -0,0 +1,188 @@
+// Package vault resolves wikilinks to the files of a vault, a directory of
+// notes, the way Obsidian does.
+package vault
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/siasmey/markdown/parse/symbols"
+)
+
+var ErrMissing = errors.New("missing target")
+
+// AmbiguousError is returned for a target that matches several files.
+type AmbiguousError struct {
+	Target string
+	Paths  []string
+}
+
+func (e *AmbiguousError) Error() string {
+	return fmt.Sprintf("wikilink target %q is ambiguous: %s", e.Target, strings.Join(e.Paths, ", "))
+}
+
+// Vault indexes the files below a root directory. Paths are slash separated
+// and relative to the root; hidden files and directories are left out.
+type Vault struct {
+	Files   []string
+	fsys    fs.FS
+	byName  map[string][]string // lower case file name to paths
+	aliases map[string][]string // lower case alias to paths
+}
+
+func Open(root string) (*Vault, error) {
+	return OpenFS(os.DirFS(root))
+}
+
+// OpenFS indexes the files of fsys and the aliases in the front matter of its
+// Markdown notes.
+func OpenFS(fsys fs.FS) (*Vault, error) {
+	v := &Vault{fsys: fsys, byName: map[string][]string{}, aliases: map[string][]string{}}
+	err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p != "." && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		v.Files = append(v.Files, p)
+		name := strings.ToLower(d.Name())
+		v.byName[name] = append(v.byName[name], p)
+		if !isNote(p) {
+			return nil
+		}
+
+		res, err := v.parse(p)
+		if err != nil {
+			return err
+		}
+		for _, alias := range res.Aliases {
+			key := strings.ToLower(strings.TrimSpace(alias.Value))
+			v.aliases[key] = append(v.aliases[key], p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// Resolve returns the path of the file a wikilink target refers to. Targets
+// are matched case-insensitively, with or without the .md extension of
+// notes: a path from the root first, then the end of a path that only one
+// file has and last the aliases of notes.
+func (v *Vault) Resolve(target string) (string, error) {
+	target = strings.TrimPrefix(strings.TrimSpace(target), "/")
+	lower := strings.ToLower(target)
+	name := path.Base(lower)
+
+	candidates := append(append([]string{}, v.byName[name]...), v.byName[name+".md"]...)
+	matches := []string{}
+	for _, p := range candidates {
+		file := strings.ToLower(p)
+		if file == lower || file == lower+".md" {
+			return p, nil
+		}
+		if strings.HasSuffix(file, "/"+lower) || strings.HasSuffix(file, "/"+lower+".md") {
+			matches = append(matches, p)
+		}
+	}
+	if len(matches) == 0 {
+		matches = v.aliases[lower]
+	}
+
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("wikilink target %q: %w", target, ErrMissing)
+	case 1:
+		return matches[0], nil
+	}
+	paths := append([]string{}, matches...)
+	sort.Strings(paths)
+	return "", &AmbiguousError{Target: target, Paths: paths}
+}
+
+// Link returns the shortest wikilink target that resolves to the file at p.
+func (v *Vault) Link(p string) string {
+	target := p
+	if isNote(p) {
+		target = strings.TrimSuffix(p, path.Ext(p))
+	}
+
+	segments := strings.Split(target, "/")
+	for i := len(segments) - 1; i > 0; i-- {
+		short := strings.Join(segments[i:], "/")
+		if resolved, err := v.Resolve(short); err == nil && resolved == p {
+			return short
+		}
+	}
+	return target
+}
+
+// Problem is a wikilink or embed of a note whose target is missing or
+// ambiguous.
+type Problem struct {
+	Path string
+	Link symbols.Symbol
+	Err  error
+}
+
+func (p Problem) Error() string {
+	return fmt.Sprintf("%s line %d column %d: %s", p.Path, p.Link.LineNo+1, p.Link.CharStart, p.Err)
+}
+
+// Check resolves the wikilinks and embeds of all notes and returns those that
+// cannot be resolved. Links to headings or blocks of the same note are not
+// checked.
+func (v *Vault) Check() ([]Problem, error) {
+	problems := []Problem{}
+	for _, p := range v.Files {
+		if !isNote(p) {
+			continue
+		}
+
+		res, err := v.parse(p)
+		if err != nil {
+			return problems, err
+		}
+		links := append(append([]symbols.Symbol{}, res.WikiLinks...), res.Embeds...)
+		sort.SliceStable(links, func(i, j int) bool { return links[i].Offset < links[j].Offset })
+		for _, link := range links {
+			if link.Target == nil {
+				continue
+			}
+			if _, err := v.Resolve(link.Target.Value); err != nil {
+				problems = append(problems, Problem{Path: p, Link: link, Err: err})
+			}
+		}
+	}
+	return problems, nil
+}
+
+// parse reads the symbols of a note.
+func (v *Vault) parse(p string) (symbols.Symbols, error) {
+	f, err := v.fsys.Open(p)
+	if err != nil {
+		return symbols.Symbols{}, err
+	}
+	defer f.Close()
+
+	return symbols.ParseReader(f)
+}
+
+func isNote(p string) bool {
+	return strings.EqualFold(path.Ext(p), ".md")
+}
@@ -0,0 +1,149 @@
+package vault
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func testVault(t *testing.T) *Vault {
+	t.Helper()
+	v, err := OpenFS(fstest.MapFS{
+		"Index.md":              {Data: []byte("# Index\n[[Some Note]] [[missing]] [[Todo]] ![[logo.png]] [[#Top]]\n")},
+		"notes/Some Note.md":    {Data: []byte("---\naliases: [Other Name, SN]\n---\n# Some Note\n")},
+		"notes/todo.md":         {Data: []byte("[[index#Index]]\n")},
+		"archive/todo.md":       {Data: []byte("")},
+		"archive/Index.md":      {Data: []byte("")},
+		"assets/logo.png":       {Data: []byte{0x89}},
+		".obsidian/app.json":    {Data: []byte("{}")},
+		".obsidian/Hidden.md":   {Data: []byte("[[nowhere]]")},
+		"notes/.draft.md":       {Data: []byte("[[nowhere]]")},
+		"notes/broken front.md": {Data: []byte("---\naliases: [unclosed\n---\n")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestOpenFSShouldIndexVisibleFiles(t *testing.T) {
+	v := testVault(t)
+	expected := []string{"Index.md", "archive/Index.md", "archive/todo.md", "assets/logo.png", "notes/Some Note.md", "notes/broken front.md", "notes/todo.md"}
+
+	if !reflect.DeepEqual(v.Files, expected) {
+		t.Errorf("OpenFS returned files %q, expected %q", v.Files, expected)
+	}
+}
+
+func TestResolveShouldFindTargets(t *testing.T) {
+	v := testVault(t)
+	tests := map[string]string{
+		"Some Note":             "notes/Some Note.md",
+		"some note":             "notes/Some Note.md",
+		"Some Note.md":          "notes/Some Note.md",
+		"notes/some note":       "notes/Some Note.md",
+		"/notes/Some Note":      "notes/Some Note.md",
+		"index":                 "Index.md",
+		"archive/index":         "archive/Index.md",
+		"notes/todo":            "notes/todo.md",
+		"logo.png":              "assets/logo.png",
+		"other name":            "notes/Some Note.md",
+		"SN":                    "notes/Some Note.md",
+		" Some Note ":           "notes/Some Note.md",
+		"assets/LOGO.PNG":       "assets/logo.png",
+		"notes/broken front.md": "notes/broken front.md",
+	}
+
+	for target, expected := range tests {
+		if got, err := v.Resolve(target); got != expected || err != nil {
+			t.Errorf("Resolve(%q) returned %q, %v, expected %q", target, got, err, expected)
+		}
+	}
+}
+
+func TestResolveShouldReportMissingTargets(t *testing.T) {
+	v := testVault(t)
+	for _, target := range []string{"missing", "logo", "Hidden", "app.json", "es/todo", "Some"} {
+		if got, err := v.Resolve(target); !errors.Is(err, ErrMissing) {
+			t.Errorf("Resolve(%q) returned %q, %v, expected %v", target, got, err, ErrMissing)
+		}
+	}
+}
+
+func TestResolveShouldReportAmbiguousTargets(t *testing.T) {
+	v := testVault(t)
+	_, err := v.Resolve("Todo")
+
+	var ambiguous *AmbiguousError
+	if !errors.As(err, &ambiguous) {
+		t.Fatalf("Resolve(%q) returned %v, expected an AmbiguousError", "Todo", err)
+	}
+	expected := []string{"archive/todo.md", "notes/todo.md"}
+	if ambiguous.Target != "Todo" || !reflect.DeepEqual(ambiguous.Paths, expected) {
+		t.Errorf("Resolve(%q) returned %q, %q, expected %q", "Todo", ambiguous.Target, ambiguous.Paths, expected)
+	}
+}
+
+func TestLinkShouldReturnShortestUniqueTarget(t *testing.T) {
+	v := testVault(t)
+	tests := map[string]string{
+		"Index.md":           "Index",
+		"archive/Index.md":   "archive/Index",
+		"notes/Some Note.md": "Some Note",
+		"notes/todo.md":      "notes/todo",
+		"assets/logo.png":    "logo.png",
+	}
+
+	for p, expected := range tests {
+		if got := v.Link(p); got != expected {
+			t.Errorf("Link(%q) returned %q, expected %q", p, got, expected)
+		}
+	}
+}
+
+func TestCheckShouldReportUnresolvedLinks(t *testing.T) {
+	v := testVault(t)
+	problems, err := v.Check()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		`Index.md line 2 column 15: wikilink target "missing": missing target`,
+		`Index.md line 2 column 27: wikilink target "Todo" is ambiguous: archive/todo.md, notes/todo.md`,
+	}
+	got := []string{}
+	for _, p := range problems {
+		got = append(got, p.Error())
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Check returned %q, expected %q", got, expected)
+	}
+}
+
+func TestOpenShouldReadDirectory(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "notes"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "notes", "Note.md"), []byte("# Note\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := Open(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := v.Resolve("note"); got != "notes/Note.md" || err != nil {
+		t.Errorf("Resolve(%q) returned %q, %v, expected %q", "note", got, err, "notes/Note.md")
+	}
+}
+
+func TestOpenShouldReturnMissingRoot(t *testing.T) {
+	if _, err := Open(filepath.Join(t.TempDir(), "nope")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Open returned %v, expected %v", err, os.ErrNotExist)
+	}
+}